@@ -0,0 +1,211 @@
+// Package activity 运动记录文件的解析
+package activity
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"time"
+)
+
+// Format 运动记录文件格式
+type Format string
+
+const (
+	FIT Format = "fit"
+	TCX Format = "tcx"
+	GPX Format = "gpx"
+)
+
+// DetectFormat 根据文件内容判断格式
+func DetectFormat(data []byte) (Format, error) {
+	if len(data) >= 12 && string(data[8:12]) == ".FIT" {
+		return FIT, nil
+	}
+	head := data[:min(len(data), 1024)]
+	switch {
+	case bytes.Contains(head, []byte("<TrainingCenterDatabase")):
+		return TCX, nil
+	case bytes.Contains(head, []byte("<gpx")):
+		return GPX, nil
+	default:
+		return "", errors.New("无法识别的运动记录文件格式")
+	}
+}
+
+// Decode 使用给定格式解析运动记录文件
+func Decode(r io.Reader, format Format) ([]Record, error) {
+	switch format {
+	case FIT:
+		return DecodeFIT(r)
+	case TCX:
+		return DecodeTCX(r)
+	case GPX:
+		return DecodeGPX(r)
+	default:
+		return nil, errors.New("不支持的运动记录文件格式: " + string(format))
+	}
+}
+
+// Record 运动记录中的一个采样点，未记录的值为 NaN
+type Record struct {
+	Time      time.Time
+	Power     float64 // 功率（瓦特）
+	HeartRate float64 // 心率（次/分钟）
+	Cadence   float64 // 踏频（转/分钟）
+	Speed     float64 // 速度（米/秒）
+	Altitude  float64 // 海拔（米）
+	Distance  float64 // 累计距离（米）
+}
+
+// NewRecord 创建所有值均未记录的采样点
+func NewRecord(t time.Time) Record {
+	nan := math.NaN()
+	return Record{
+		Time:      t,
+		Power:     nan,
+		HeartRate: nan,
+		Cadence:   nan,
+		Speed:     nan,
+		Altitude:  nan,
+		Distance:  nan,
+	}
+}
+
+// MaxInterpolateGap 不超过该时长（秒）的缺失使用线性插值填补，更长的缺失视为暂停
+const MaxInterpolateGap = 5
+
+const (
+	// maxSegmentGap 相邻采样点间隔超过该时长时分段，只保留采样点最多的一段，
+	// 用于排除损坏的时间戳（例如 FIT 纪元 1989 年的采样点）
+	maxSegmentGap = 12 * time.Hour
+)
+
+// MaxDuration 重采样的最长时长，防止时间跨度过大时分配过多内存
+const MaxDuration = 48 * time.Hour
+
+// Stream 1 Hz 等间隔的数据流，下标 i 对应 Start 之后第 i 秒
+//
+// 重复时间戳的采样取平均；不超过 MaxInterpolateGap 的缺失线性插值；
+// 更长的缺失视为暂停，功率、踏频、速度与心率填 0，海拔与距离保持上一个值。
+type Stream struct {
+	Start     time.Time
+	Power     []float64
+	HeartRate []float64
+	Cadence   []float64
+	Speed     []float64
+	Altitude  []float64
+	Distance  []float64
+}
+
+// Len 返回数据流的长度（秒）
+func (s *Stream) Len() int {
+	return len(s.Power)
+}
+
+// Resample 将采样点重采样为 1 Hz 数据流
+func Resample(records []Record) (*Stream, error) {
+	if len(records) == 0 {
+		return nil, errors.New("运动记录中没有采样点")
+	}
+	records = slices.Clone(records)
+	slices.SortStableFunc(records, func(a, b Record) int {
+		return a.Time.Compare(b.Time)
+	})
+	records = longestSegment(records)
+	if d := records[len(records)-1].Time.Sub(records[0].Time); d > MaxDuration {
+		return nil, fmt.Errorf("运动记录时长过长: %s", d.Round(time.Second))
+	}
+
+	start := records[0].Time.Truncate(time.Second)
+	n := int(records[len(records)-1].Time.Sub(start)/time.Second) + 1
+	s := &Stream{Start: start}
+	channels := []struct {
+		out   *[]float64
+		value func(Record) float64
+		pause func(prev float64) float64
+	}{
+		{&s.Power, func(r Record) float64 { return r.Power }, zero},
+		{&s.HeartRate, func(r Record) float64 { return r.HeartRate }, zero},
+		{&s.Cadence, func(r Record) float64 { return r.Cadence }, zero},
+		{&s.Speed, func(r Record) float64 { return r.Speed }, zero},
+		{&s.Altitude, func(r Record) float64 { return r.Altitude }, hold},
+		{&s.Distance, func(r Record) float64 { return r.Distance }, hold},
+	}
+	for _, c := range channels {
+		*c.out = resampleChannel(records, start, n, c.value, c.pause)
+	}
+	return s, nil
+}
+
+// longestSegment 在间隔超过 maxSegmentGap 处将已排序的采样点分段，返回采样点最多的一段
+func longestSegment(records []Record) []Record {
+	best, from := records[:0], 0
+	for i := 1; i <= len(records); i++ {
+		if i < len(records) && records[i].Time.Sub(records[i-1].Time) <= maxSegmentGap {
+			continue
+		}
+		if i-from > len(best) {
+			best = records[from:i]
+		}
+		from = i
+	}
+	return best
+}
+
+func zero(float64) float64 { return 0 }
+
+func hold(prev float64) float64 { return prev }
+
+// resampleChannel 对单个通道重采样，pause 决定暂停期间的取值
+func resampleChannel(records []Record, start time.Time, n int, value func(Record) float64, pause func(prev float64) float64) []float64 {
+	sum := make([]float64, n)
+	count := make([]int, n)
+	for _, r := range records {
+		v := value(r)
+		if math.IsNaN(v) {
+			continue
+		}
+		i := int(r.Time.Sub(start) / time.Second)
+		sum[i] += v
+		count[i]++
+	}
+
+	out := make([]float64, n)
+	last := -1 // 上一个有值的下标
+	for i := range n {
+		if count[i] == 0 {
+			continue
+		}
+		out[i] = sum[i] / float64(count[i])
+		gap := i - last - 1
+		switch {
+		case last < 0:
+			// 开头的缺失按暂停处理，海拔与距离取第一个值
+			for j := range i {
+				out[j] = pause(out[i])
+			}
+		case gap > 0 && gap <= MaxInterpolateGap:
+			for j := last + 1; j < i; j++ {
+				frac := float64(j-last) / float64(i-last)
+				out[j] = out[last] + (out[i]-out[last])*frac
+			}
+		case gap > MaxInterpolateGap:
+			for j := last + 1; j < i; j++ {
+				out[j] = pause(out[last])
+			}
+		}
+		last = i
+	}
+	if last < 0 {
+		// 整个通道都没有记录
+		return out
+	}
+	for j := last + 1; j < n; j++ {
+		out[j] = pause(out[last])
+	}
+	return out
+}
@@ -0,0 +1,78 @@
+package activity_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Equationzhao/power/activity"
+)
+
+func TestResample(t *testing.T) {
+	start := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	record := func(sec float64, power float64) activity.Record {
+		r := activity.NewRecord(start.Add(time.Duration(sec * float64(time.Second))))
+		r.Power = power
+		r.Altitude = 100 + sec
+		return r
+	}
+	records := []activity.Record{
+		record(0, 100),
+		record(1, 200),
+		record(1.5, 300), // 重复的秒取平均
+		record(4, 400),   // 2 秒缺失，插值
+		record(20, 500),  // 15 秒缺失，视为暂停
+	}
+
+	s, err := activity.Resample(records)
+	if err != nil {
+		t.Fatalf("重采样失败: %v", err)
+	}
+	if s.Len() != 21 {
+		t.Fatalf("长度 = %d, 期望 21", s.Len())
+	}
+	want := map[int]float64{0: 100, 1: 250, 2: 300, 3: 350, 4: 400, 10: 0, 20: 500}
+	for i, p := range want {
+		if math.Abs(s.Power[i]-p) > 1e-9 {
+			t.Errorf("Power[%d] = %.1f, 期望 %.1f", i, s.Power[i], p)
+		}
+	}
+	if s.Altitude[10] != 104 {
+		t.Errorf("暂停期间海拔应保持 104, 得到 %.1f", s.Altitude[10])
+	}
+	if s.HeartRate[5] != 0 {
+		t.Errorf("未记录的心率应为 0, 得到 %.1f", s.HeartRate[5])
+	}
+}
+
+// TestResampleTimestampRange 测试损坏的时间戳不会使数据流过长
+func TestResampleTimestampRange(t *testing.T) {
+	start := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	record := func(at time.Time) activity.Record {
+		r := activity.NewRecord(at)
+		r.Power = 200
+		return r
+	}
+
+	// FIT 纪元的采样点与正常的记录相差几十年，应被排除
+	records := []activity.Record{record(time.Date(1989, 12, 31, 0, 0, 0, 0, time.UTC))}
+	for i := range 60 {
+		records = append(records, record(start.Add(time.Duration(i)*time.Second)))
+	}
+	s, err := activity.Resample(records)
+	if err != nil {
+		t.Fatalf("重采样失败: %v", err)
+	}
+	if s.Len() != 60 || !s.Start.Equal(start) {
+		t.Errorf("数据流从 %s 开始，长度 %d，期望从 %s 开始，长度 60", s.Start, s.Len(), start)
+	}
+
+	// 每段间隔都不大，但总时长超过上限
+	records = records[:0]
+	for i := range 20 {
+		records = append(records, record(start.Add(time.Duration(i)*6*time.Hour)))
+	}
+	if _, err := activity.Resample(records); err == nil {
+		t.Error("时长超过上限应返回错误")
+	}
+}
@@ -0,0 +1,287 @@
+package activity
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// FIT 协议中的常量
+// https://developer.garmin.com/fit/protocol/
+const (
+	fitRecordMessage  = 20  // record 消息的全局编号
+	fitTimestampField = 253 // 所有消息通用的 timestamp 字段
+
+	// record 消息的字段编号
+	fitAltitude         = 2
+	fitHeartRate        = 3
+	fitCadence          = 4
+	fitDistance         = 5
+	fitSpeed            = 6
+	fitPower            = 7
+	fitEnhancedSpeed    = 73
+	fitEnhancedAltitude = 78
+)
+
+// fitEpoch FIT 时间戳的起点 1989-12-31 00:00:00 UTC
+var fitEpoch = time.Date(1989, 12, 31, 0, 0, 0, 0, time.UTC)
+
+type fitFieldDefinition struct {
+	num  byte
+	size byte
+}
+
+type fitDefinition struct {
+	byteOrder     binary.ByteOrder
+	globalMessage uint16
+	fields        []fitFieldDefinition
+	devDataSize   int // 开发者字段的总字节数，解析时跳过
+}
+
+// DecodeFIT 解析 FIT 文件中的 record 消息，返回按时间排序的采样点
+//
+// 支持普通与压缩时间戳的记录头，开发者字段会被跳过；文件末尾的 CRC 不为 0 时会进行校验。
+func DecodeFIT(r io.Reader) ([]Record, error) {
+	br := bufio.NewReader(r)
+	crc := &fitCRC{}
+
+	headerSize, err := br.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("读取 FIT 文件头失败: %w", err)
+	}
+	if headerSize != 12 && headerSize != 14 {
+		return nil, fmt.Errorf("无效的 FIT 文件头长度: %d", headerSize)
+	}
+	header := make([]byte, headerSize-1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("读取 FIT 文件头失败: %w", err)
+	}
+	if string(header[7:11]) != ".FIT" {
+		return nil, errors.New("不是 FIT 文件")
+	}
+	crc.update([]byte{headerSize})
+	crc.update(header)
+	dataSize := binary.LittleEndian.Uint32(header[3:7])
+
+	d := &fitDecoder{
+		r:           &countingReader{r: br, crc: crc, remaining: int64(dataSize)},
+		definitions: make(map[byte]*fitDefinition),
+	}
+	for d.r.remaining > 0 {
+		if err := d.next(); err != nil {
+			return nil, err
+		}
+	}
+
+	var trailer [2]byte
+	if _, err := io.ReadFull(br, trailer[:]); err == nil {
+		if want := binary.LittleEndian.Uint16(trailer[:]); want != 0 && want != crc.sum {
+			return nil, errors.New("FIT 文件 CRC 校验失败")
+		}
+	}
+	return d.records, nil
+}
+
+type fitDecoder struct {
+	r             *countingReader
+	definitions   map[byte]*fitDefinition
+	lastTimestamp uint32
+	records       []Record
+}
+
+// next 解析一条消息
+func (d *fitDecoder) next() error {
+	h, err := d.r.readByte()
+	if err != nil {
+		return err
+	}
+
+	if h&0x80 != 0 {
+		// 压缩时间戳记录头：低 5 位为相对上一个时间戳的偏移
+		local := (h >> 5) & 0x03
+		offset := uint32(h & 0x1f)
+		timestamp := d.lastTimestamp&^0x1f + offset
+		if offset < d.lastTimestamp&0x1f {
+			timestamp += 0x20
+		}
+		return d.readData(local, &timestamp)
+	}
+
+	local := h & 0x0f
+	if h&0x40 != 0 {
+		return d.readDefinition(local, h&0x20 != 0)
+	}
+	return d.readData(local, nil)
+}
+
+func (d *fitDecoder) readDefinition(local byte, developer bool) error {
+	buf, err := d.r.read(5)
+	if err != nil {
+		return err
+	}
+	def := &fitDefinition{byteOrder: binary.LittleEndian}
+	if buf[1] == 1 {
+		def.byteOrder = binary.BigEndian
+	}
+	def.globalMessage = def.byteOrder.Uint16(buf[2:4])
+
+	fields, err := d.r.read(int(buf[4]) * 3)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(fields); i += 3 {
+		def.fields = append(def.fields, fitFieldDefinition{num: fields[i], size: fields[i+1]})
+	}
+
+	if developer {
+		n, err := d.r.readByte()
+		if err != nil {
+			return err
+		}
+		devFields, err := d.r.read(int(n) * 3)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < len(devFields); i += 3 {
+			def.devDataSize += int(devFields[i+1])
+		}
+	}
+
+	d.definitions[local] = def
+	return nil
+}
+
+// readData 解析数据消息，timestamp 不为空时表示来自压缩时间戳记录头
+func (d *fitDecoder) readData(local byte, timestamp *uint32) error {
+	def, ok := d.definitions[local]
+	if !ok {
+		return fmt.Errorf("FIT 数据消息缺少定义: 本地消息类型 %d", local)
+	}
+
+	values := make(map[byte]uint64, len(def.fields))
+	for _, field := range def.fields {
+		buf, err := d.r.read(int(field.size))
+		if err != nil {
+			return err
+		}
+		if v, ok := fitUint(buf, def.byteOrder); ok {
+			values[field.num] = v
+		}
+	}
+	if _, err := d.r.read(def.devDataSize); err != nil {
+		return err
+	}
+
+	if v, ok := values[fitTimestampField]; ok {
+		d.lastTimestamp = uint32(v)
+	} else if timestamp != nil {
+		d.lastTimestamp = *timestamp
+		values[fitTimestampField] = uint64(*timestamp)
+	}
+
+	if def.globalMessage == fitRecordMessage {
+		d.appendRecord(values)
+	}
+	return nil
+}
+
+func (d *fitDecoder) appendRecord(values map[byte]uint64) {
+	ts, ok := values[fitTimestampField]
+	if !ok {
+		return
+	}
+	record := NewRecord(fitEpoch.Add(time.Duration(ts) * time.Second))
+	if v, ok := values[fitPower]; ok {
+		record.Power = float64(v)
+	}
+	if v, ok := values[fitHeartRate]; ok {
+		record.HeartRate = float64(v)
+	}
+	if v, ok := values[fitCadence]; ok {
+		record.Cadence = float64(v)
+	}
+	if v, ok := values[fitEnhancedSpeed]; ok {
+		record.Speed = float64(v) / 1000
+	} else if v, ok := values[fitSpeed]; ok {
+		record.Speed = float64(v) / 1000
+	}
+	if v, ok := values[fitEnhancedAltitude]; ok {
+		record.Altitude = float64(v)/5 - 500
+	} else if v, ok := values[fitAltitude]; ok {
+		record.Altitude = float64(v)/5 - 500
+	}
+	if v, ok := values[fitDistance]; ok {
+		record.Distance = float64(v) / 100
+	}
+	d.records = append(d.records, record)
+}
+
+// fitUint 将 1、2、4 字节的字段解析为无符号整数，无效值（全 1）返回 false
+func fitUint(buf []byte, order binary.ByteOrder) (uint64, bool) {
+	switch len(buf) {
+	case 1:
+		return uint64(buf[0]), buf[0] != math.MaxUint8
+	case 2:
+		v := order.Uint16(buf)
+		return uint64(v), v != math.MaxUint16
+	case 4:
+		v := order.Uint32(buf)
+		return uint64(v), v != math.MaxUint32
+	default:
+		return 0, false
+	}
+}
+
+// countingReader 限制读取的字节数并计算 CRC
+type countingReader struct {
+	r         *bufio.Reader
+	crc       *fitCRC
+	remaining int64
+}
+
+func (c *countingReader) read(n int) ([]byte, error) {
+	if int64(n) > c.remaining {
+		return nil, errors.New("FIT 文件数据不完整")
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(c.r, buf); err != nil {
+		return nil, fmt.Errorf("FIT 文件数据不完整: %w", err)
+	}
+	c.remaining -= int64(n)
+	c.crc.update(buf)
+	return buf, nil
+}
+
+func (c *countingReader) readByte() (byte, error) {
+	buf, err := c.read(1)
+	if err != nil {
+		return 0, err
+	}
+	return buf[0], nil
+}
+
+// fitCRC FIT 协议使用的 CRC-16
+type fitCRC struct {
+	sum uint16
+}
+
+var fitCRCTable = [16]uint16{
+	0x0000, 0xCC01, 0xD801, 0x1400, 0xF001, 0x3C00, 0x2800, 0xE401,
+	0xA001, 0x6C00, 0x7800, 0xB401, 0x5000, 0x9C01, 0x8801, 0x4400,
+}
+
+func (c *fitCRC) update(buf []byte) {
+	for _, b := range buf {
+		tmp := fitCRCTable[c.sum&0xf]
+		c.sum = (c.sum >> 4) & 0x0fff
+		c.sum ^= tmp ^ fitCRCTable[b&0xf]
+
+		tmp = fitCRCTable[c.sum&0xf]
+		c.sum = (c.sum >> 4) & 0x0fff
+		c.sum ^= tmp ^ fitCRCTable[(b>>4)&0xf]
+	}
+}
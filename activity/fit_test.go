@@ -0,0 +1,101 @@
+package activity
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+// fitBuilder 构造测试用的 FIT 文件
+type fitBuilder struct {
+	data bytes.Buffer
+}
+
+func (b *fitBuilder) bytes() []byte {
+	header := make([]byte, 14)
+	header[0] = 14
+	header[1] = 0x20
+	binary.LittleEndian.PutUint16(header[2:], 2132)
+	binary.LittleEndian.PutUint32(header[4:], uint32(b.data.Len()))
+	copy(header[8:], ".FIT")
+	crc := &fitCRC{}
+	crc.update(header[:12])
+	binary.LittleEndian.PutUint16(header[12:], crc.sum)
+
+	file := append(header, b.data.Bytes()...)
+	crc = &fitCRC{}
+	crc.update(file)
+	return binary.LittleEndian.AppendUint16(file, crc.sum)
+}
+
+func TestDecodeFIT(t *testing.T) {
+	b := &fitBuilder{}
+	start := uint32(1_000_000_000)
+
+	// 定义消息：本地类型 0，大端序，record 消息，带一个 2 字节的开发者字段
+	b.data.Write([]byte{0x60, 0, 1, 0, fitRecordMessage, 6})
+	b.data.Write([]byte{
+		fitTimestampField, 4, 0x86,
+		fitPower, 2, 0x84,
+		fitHeartRate, 1, 0x02,
+		fitCadence, 1, 0x02,
+		fitEnhancedSpeed, 4, 0x86,
+		fitEnhancedAltitude, 4, 0x86,
+	})
+	b.data.Write([]byte{1, 0, 2, 0})
+
+	writeRecord := func(h byte, timestamp *uint32, power uint16) {
+		b.data.WriteByte(h)
+		if timestamp != nil {
+			b.data.Write(binary.BigEndian.AppendUint32(nil, *timestamp))
+		} else {
+			// 压缩时间戳记录中 timestamp 字段仍按定义写入无效值
+			b.data.Write(binary.BigEndian.AppendUint32(nil, math.MaxUint32))
+		}
+		b.data.Write(binary.BigEndian.AppendUint16(nil, power))
+		b.data.Write([]byte{150, 90})
+		b.data.Write(binary.BigEndian.AppendUint32(nil, 8500))     // 8.5 m/s
+		b.data.Write(binary.BigEndian.AppendUint32(nil, 2500+500)) // 100 m
+		b.data.Write([]byte{0xAB, 0xCD})                           // 开发者字段
+	}
+
+	writeRecord(0x00, &start, 250)
+	next := start + 1
+	writeRecord(0x00, &next, math.MaxUint16) // 无效功率
+	// 压缩时间戳：偏移为 (start + 2) 的低 5 位
+	writeRecord(0x80|byte((start+2)&0x1f), nil, 300)
+
+	records, err := DecodeFIT(bytes.NewReader(b.bytes()))
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("采样点数量 = %d, 期望 3", len(records))
+	}
+
+	first := records[0]
+	if want := fitEpoch.Add(time.Duration(start) * time.Second); !first.Time.Equal(want) {
+		t.Errorf("时间 = %v, 期望 %v", first.Time, want)
+	}
+	if first.Power != 250 || first.HeartRate != 150 || first.Cadence != 90 || first.Speed != 8.5 || first.Altitude != 100 {
+		t.Errorf("采样点 = %+v", first)
+	}
+	if !math.IsNaN(first.Distance) {
+		t.Errorf("未记录的距离应为 NaN, 得到 %f", first.Distance)
+	}
+	if !math.IsNaN(records[1].Power) {
+		t.Errorf("无效功率应为 NaN, 得到 %f", records[1].Power)
+	}
+	if got := records[2].Time.Sub(first.Time); got != 2*time.Second || records[2].Power != 300 {
+		t.Errorf("压缩时间戳采样点 = %+v, 与第一个点相差 %v", records[2], got)
+	}
+
+	// 篡改数据后 CRC 校验失败
+	file := b.bytes()
+	file[20] ^= 0xff
+	if _, err := DecodeFIT(bytes.NewReader(file)); err == nil {
+		t.Error("CRC 错误时应返回错误")
+	}
+}
@@ -0,0 +1,72 @@
+package activity
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// gpxTrackpoint GPX 文件中的采样点
+//
+// 功率可能位于 Strava 导出的 <power>，或 Garmin PowerExtension 的 <PowerInWatts>；
+// 心率、踏频与速度位于 Garmin TrackPointExtension 中。
+type gpxTrackpoint struct {
+	Lat          float64    `xml:"lat,attr"`
+	Lon          float64    `xml:"lon,attr"`
+	Time         *time.Time `xml:"time"`
+	Elevation    *float64   `xml:"ele"`
+	Power        *float64   `xml:"extensions>power"`
+	PowerInWatts *float64   `xml:"extensions>PowerExtension>PowerInWatts"`
+	HeartRate    *float64   `xml:"extensions>TrackPointExtension>hr"`
+	Cadence      *float64   `xml:"extensions>TrackPointExtension>cad"`
+	Speed        *float64   `xml:"extensions>TrackPointExtension>speed"`
+}
+
+type gpxFile struct {
+	Trackpoints []gpxTrackpoint `xml:"trk>trkseg>trkpt"`
+}
+
+// earthRadius 地球平均半径（米）
+const earthRadius = 6371000.0
+
+// DecodeGPX 解析 GPX 文件中所有轨迹段的采样点，累计距离由经纬度计算，没有时间的采样点会被忽略
+func DecodeGPX(r io.Reader) ([]Record, error) {
+	var file gpxFile
+	if err := xml.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("解析 GPX 文件失败: %w", err)
+	}
+
+	records := make([]Record, 0, len(file.Trackpoints))
+	distance := 0.0
+	for i, tp := range file.Trackpoints {
+		if i > 0 {
+			prev := file.Trackpoints[i-1]
+			distance += haversine(prev.Lat, prev.Lon, tp.Lat, tp.Lon)
+		}
+		if tp.Time == nil {
+			continue
+		}
+		record := NewRecord(*tp.Time)
+		record.Distance = distance
+		setIfPresent(&record.Power, tp.PowerInWatts)
+		setIfPresent(&record.Power, tp.Power)
+		setIfPresent(&record.HeartRate, tp.HeartRate)
+		setIfPresent(&record.Cadence, tp.Cadence)
+		setIfPresent(&record.Speed, tp.Speed)
+		setIfPresent(&record.Altitude, tp.Elevation)
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// haversine 计算两个经纬度之间的大圆距离（米）
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := math.Pi / 180
+	dLat := (lat2 - lat1) * toRad
+	dLon := (lon2 - lon1) * toRad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*toRad)*math.Cos(lat2*toRad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
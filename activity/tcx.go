@@ -0,0 +1,53 @@
+package activity
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// tcxTrackpoint TCX 文件中的采样点，功率与速度位于 ActivityExtension 的 TPX 扩展中
+type tcxTrackpoint struct {
+	Time      *time.Time `xml:"Time"`
+	Altitude  *float64   `xml:"AltitudeMeters"`
+	Distance  *float64   `xml:"DistanceMeters"`
+	HeartRate *float64   `xml:"HeartRateBpm>Value"`
+	Cadence   *float64   `xml:"Cadence"`
+	Speed     *float64   `xml:"Extensions>TPX>Speed"`
+	Watts     *float64   `xml:"Extensions>TPX>Watts"`
+}
+
+type tcxDatabase struct {
+	Trackpoints []tcxTrackpoint `xml:"Activities>Activity>Lap>Track>Trackpoint"`
+}
+
+// DecodeTCX 解析 TCX 文件中所有圈的采样点，没有时间的采样点会被忽略
+func DecodeTCX(r io.Reader) ([]Record, error) {
+	var db tcxDatabase
+	if err := xml.NewDecoder(r).Decode(&db); err != nil {
+		return nil, fmt.Errorf("解析 TCX 文件失败: %w", err)
+	}
+
+	records := make([]Record, 0, len(db.Trackpoints))
+	for _, tp := range db.Trackpoints {
+		if tp.Time == nil {
+			continue
+		}
+		record := NewRecord(*tp.Time)
+		setIfPresent(&record.Power, tp.Watts)
+		setIfPresent(&record.HeartRate, tp.HeartRate)
+		setIfPresent(&record.Cadence, tp.Cadence)
+		setIfPresent(&record.Speed, tp.Speed)
+		setIfPresent(&record.Altitude, tp.Altitude)
+		setIfPresent(&record.Distance, tp.Distance)
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func setIfPresent(dst *float64, v *float64) {
+	if v != nil {
+		*dst = *v
+	}
+}
@@ -0,0 +1,117 @@
+package activity_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/Equationzhao/power/activity"
+)
+
+const testTCX = `<?xml version="1.0" encoding="UTF-8"?>
+<TrainingCenterDatabase xmlns="http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2" xmlns:ns3="http://www.garmin.com/xmlschemas/ActivityExtension/v2">
+  <Activities>
+    <Activity Sport="Biking">
+      <Lap StartTime="2025-01-01T08:00:00Z">
+        <Track>
+          <Trackpoint>
+            <Time>2025-01-01T08:00:00Z</Time>
+            <AltitudeMeters>100.0</AltitudeMeters>
+            <DistanceMeters>0.0</DistanceMeters>
+            <HeartRateBpm><Value>120</Value></HeartRateBpm>
+            <Cadence>85</Cadence>
+            <Extensions><ns3:TPX><ns3:Speed>8.0</ns3:Speed><ns3:Watts>200</ns3:Watts></ns3:TPX></Extensions>
+          </Trackpoint>
+          <Trackpoint>
+            <Time>2025-01-01T08:00:01Z</Time>
+            <Extensions><ns3:TPX><ns3:Watts>220</ns3:Watts></ns3:TPX></Extensions>
+          </Trackpoint>
+        </Track>
+      </Lap>
+      <Lap StartTime="2025-01-01T08:00:10Z">
+        <Track>
+          <Trackpoint>
+            <Time>2025-01-01T08:00:10Z</Time>
+            <Extensions><ns3:TPX><ns3:Watts>300</ns3:Watts></ns3:TPX></Extensions>
+          </Trackpoint>
+          <Trackpoint>
+            <Time>2025-01-01T08:00:10Z</Time>
+            <Extensions><ns3:TPX><ns3:Watts>400</ns3:Watts></ns3:TPX></Extensions>
+          </Trackpoint>
+        </Track>
+      </Lap>
+    </Activity>
+  </Activities>
+</TrainingCenterDatabase>`
+
+const testGPX = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="StravaGPX" xmlns="http://www.topografix.com/GPX/1/1" xmlns:gpxtpx="http://www.garmin.com/xmlschemas/TrackPointExtension/v1">
+  <trk>
+    <trkseg>
+      <trkpt lat="30.0000" lon="120.0000">
+        <ele>10.0</ele>
+        <time>2025-01-01T08:00:00Z</time>
+        <extensions>
+          <power>250</power>
+          <gpxtpx:TrackPointExtension><gpxtpx:hr>140</gpxtpx:hr><gpxtpx:cad>90</gpxtpx:cad></gpxtpx:TrackPointExtension>
+        </extensions>
+      </trkpt>
+      <trkpt lat="30.0010" lon="120.0000">
+        <ele>11.0</ele>
+        <time>2025-01-01T08:00:01Z</time>
+        <extensions><power>260</power></extensions>
+      </trkpt>
+    </trkseg>
+  </trk>
+</gpx>`
+
+func TestDecodeTCX(t *testing.T) {
+	format, err := activity.DetectFormat([]byte(testTCX))
+	if err != nil || format != activity.TCX {
+		t.Fatalf("格式 = %q, %v", format, err)
+	}
+	records, err := activity.Decode(strings.NewReader(testTCX), format)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("采样点数量 = %d, 期望 4", len(records))
+	}
+	first := records[0]
+	if first.Power != 200 || first.HeartRate != 120 || first.Cadence != 85 || first.Speed != 8 || first.Altitude != 100 {
+		t.Errorf("采样点 = %+v", first)
+	}
+	if !math.IsNaN(records[1].HeartRate) {
+		t.Errorf("未记录的心率应为 NaN, 得到 %f", records[1].HeartRate)
+	}
+
+	// 两圈之间 8 秒的间隔视为暂停，重复的时间戳取平均
+	s, err := activity.Resample(records)
+	if err != nil {
+		t.Fatalf("重采样失败: %v", err)
+	}
+	if s.Len() != 11 || s.Power[5] != 0 || s.Power[10] != 350 {
+		t.Errorf("功率流 = %v", s.Power)
+	}
+}
+
+func TestDecodeGPX(t *testing.T) {
+	format, err := activity.DetectFormat([]byte(testGPX))
+	if err != nil || format != activity.GPX {
+		t.Fatalf("格式 = %q, %v", format, err)
+	}
+	records, err := activity.Decode(strings.NewReader(testGPX), format)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("采样点数量 = %d, 期望 2", len(records))
+	}
+	if records[0].Power != 250 || records[0].HeartRate != 140 || records[0].Cadence != 90 || records[0].Altitude != 10 {
+		t.Errorf("采样点 = %+v", records[0])
+	}
+	// 纬度相差 0.001 度约为 111 米
+	if d := records[1].Distance; math.Abs(d-111.2) > 0.5 {
+		t.Errorf("累计距离 = %.1f, 期望约 111.2", d)
+	}
+}
@@ -0,0 +1,412 @@
+package criticalpower
+
+import (
+	"errors"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"slices"
+)
+
+// 贝叶斯拟合
+//
+// 数据点很少时最小二乘常得到不合理的 τ 或 W'。贝叶斯拟合为自由参数设置正态先验，
+// 假设相对残差服从 N(0, σ²)，删失点的似然为测试结果不低于该点的概率，
+// σ 的先验为尺度 DefaultNoiseScale 的半正态分布，
+// 使用自适应 Metropolis–Hastings 采样后验分布，以后验均值作为模型参数。
+const (
+	DefaultMCMCSamples = 4000 // 默认保留的后验样本数，平均分配到各条链
+	DefaultNoiseScale  = 0.05 // 相对残差标准差 σ 的半正态先验尺度，即测试本身约 5% 的误差
+	DefaultPriorMass   = 75.0 // 未给出体重时用于缩放先验的体重（kg）
+	mcmcChains         = 4    // 链数
+	mcmcBurnIn         = 3000 // 每条链丢弃的预热迭代次数，预热期间调整提议分布
+	mcmcThin           = 5    // 每隔多少次迭代保留一个样本
+	mcmcAdaptInterval  = 500  // 预热后半段重新估计提议分布协方差的间隔
+	mcmcSeed           = 0xba7e5
+)
+
+// Prior 参数的正态先验，参数不能低于模型的下界，因此实际为截断正态分布
+type Prior struct {
+	Mean float64
+	SD   float64
+}
+
+// Priors 以参数名为键的先验，没有先验的参数在下界之上使用均匀先验
+type Priors map[string]Prior
+
+// DefaultPriors 返回按体重（kg）缩放的人群先验，体重不大于 0 时使用 DefaultPriorMass
+//
+// CP 约 3.5 ± 1 W/kg，W' 约 250 ± 100 J/kg，Pmax 约 13 ± 4 W/kg。
+// τ 的含义随模型不同：三参数模型中约 15 ± 10 秒，指数模型中约 40 ± 25 秒。
+func DefaultPriors(modelType ModelType, mass float64) Priors {
+	if mass <= 0 {
+		mass = DefaultPriorMass
+	}
+	priors := Priors{
+		ParamCP:     {Mean: 3.5 * mass, SD: 1 * mass},
+		ParamWprime: {Mean: 250 * mass, SD: 100 * mass},
+		ParamPmax:   {Mean: 13 * mass, SD: 4 * mass},
+		ParamTau:    {Mean: 15, SD: 10},
+		ParamA:      {Mean: 0.25 * mass, SD: 0.25 * mass},
+	}
+	if modelType == Exponential {
+		priors[ParamTau] = Prior{Mean: 40, SD: 25}
+	}
+	return priors
+}
+
+// Validate 检查先验是否有效
+func (p Priors) Validate() error {
+	for name, prior := range p {
+		if prior.SD <= 0 || math.IsNaN(prior.Mean) || math.IsInf(prior.Mean, 0) {
+			return errors.New("参数 " + name + " 的先验均值必须有限且标准差大于0")
+		}
+	}
+	return nil
+}
+
+// WithPriors 按参数名覆盖贝叶斯拟合的先验，其余参数使用 DefaultPriors(模型类型, 体重)，仅在使用 Bayesian 拟合算法时生效
+// 默认先验在拟合时按模型类型生成，因此同一组选项可以用于不同类型的模型
+func WithPriors(priors Priors) ModelOption {
+	return func(m *CriticalPowerModel) {
+		m.priors = priors
+	}
+}
+
+// WithPriorMass 设置默认先验使用的体重（千克），不大于 0 时使用 DefaultPriorMass
+func WithPriorMass(mass float64) ModelOption {
+	return func(m *CriticalPowerModel) {
+		m.priorMass = mass
+	}
+}
+
+// WithMCMCSamples 设置贝叶斯拟合保留的后验样本数
+func WithMCMCSamples(samples int) ModelOption {
+	return func(m *CriticalPowerModel) {
+		if samples <= 0 {
+			samples = DefaultMCMCSamples
+		}
+		m.mcmcSamples = samples
+	}
+}
+
+// Posterior 贝叶斯拟合的后验分布
+type Posterior struct {
+	Samples        int                 // 保留的样本数
+	Chains         int                 // 链数
+	Level          float64             // 可信水平
+	AcceptanceRate float64             // 预热后的接受率
+	Means          map[string]float64  // 后验均值，包括 cp、wprime、pmax、tau 以及模型的其他自由参数
+	Intervals      map[string]Interval // 等尾可信区间
+	RHat           map[string]float64  // 自由参数的潜在尺度缩减因子，接近 1 说明各条链已收敛
+	Sigma          float64             // 相对残差标准差的后验均值
+	Priors         Priors              // 拟合使用的自由参数的先验
+
+	draws []*CriticalPowerModel // 后验样本
+	noise []float64             // 每个样本对应的一次相对误差抽样，用于后验预测
+}
+
+// CredibleBand 返回时间 t 的预测功率曲线的可信区间
+func (p *Posterior) CredibleBand(t float64) Interval {
+	values := make([]float64, len(p.draws))
+	for i, draw := range p.draws {
+		values[i] = draw.PredictPower(t)
+	}
+	return percentileInterval(values, p.Level)
+}
+
+// PredictiveBand 返回时间 t 的后验预测区间，即下一次测试功率的分布，包含测试本身的误差
+func (p *Posterior) PredictiveBand(t float64) Interval {
+	values := make([]float64, len(p.draws))
+	for i, draw := range p.draws {
+		values[i] = draw.PredictPower(t) * (1 + p.noise[i])
+	}
+	return percentileInterval(values, p.Level)
+}
+
+// PredictiveMean 返回时间 t 的后验预测均值
+func (p *Posterior) PredictiveMean(t float64) float64 {
+	sum := 0.0
+	for _, draw := range p.draws {
+		sum += draw.PredictPower(t)
+	}
+	return sum / float64(len(p.draws))
+}
+
+// logPosterior 返回参数 theta（最后一个元素为 ln σ）的未归一化对数后验密度
+func logPosterior(trial *CriticalPowerModel, data []PowerTimePoint, priors []*Prior, lower, theta []float64) float64 {
+	params := theta[:len(theta)-1]
+	logSigma := theta[len(theta)-1]
+	lp := 0.0
+	for i, v := range params {
+		if v < lower[i] {
+			return math.Inf(-1)
+		}
+		if p := priors[i]; p != nil {
+			z := (v - p.Mean) / p.SD
+			lp -= z * z / 2
+		}
+	}
+
+	// σ 的半正态先验，在 ln σ 上采样需要加上雅可比项 ln σ
+	sigma := math.Exp(logSigma)
+	lp += logSigma - sigma*sigma/(2*DefaultNoiseScale*DefaultNoiseScale)
+
+	trial.form().apply(trial, params)
+	for _, point := range data {
+		r := (trial.PredictPower(point.Time) - point.Power) / point.Power
+		if math.IsNaN(r) || math.IsInf(r, 0) {
+			return math.Inf(-1)
+		}
+		if point.Censored {
+			// 删失点：最大努力的测试结果不低于该功率的概率 Φ(r/σ)
+			lp += logNormalCDF(r / sigma)
+			continue
+		}
+		lp -= logSigma + r*r/(2*sigma*sigma)
+	}
+	return lp
+}
+
+// logNormalCDF 返回标准正态分布函数的对数 ln Φ(x)
+func logNormalCDF(x float64) float64 {
+	if x < -30 {
+		// 尾部的渐近展开，避免 Erfc 下溢
+		return -x*x/2 - math.Log(-x) - 0.5*math.Log(2*math.Pi)
+	}
+	return math.Log(0.5 * math.Erfc(-x/math.Sqrt2))
+}
+
+// fitBayesian 从 Levenberg–Marquardt 的结果出发运行多条自适应 Metropolis 链，返回后验均值与后验分布
+func (m *CriticalPowerModel) fitBayesian(data []PowerTimePoint, estimatedMinCP, estimatedMaxCP, maxPower float64) ([]float64, *Posterior, error) {
+	f := m.form()
+	names := f.names()
+	lower := f.lower()
+	d := len(names) + 1
+
+	priors := DefaultPriors(m.Type(), m.priorMass)
+	maps.Copy(priors, m.priors)
+	if err := priors.Validate(); err != nil {
+		return nil, nil, err
+	}
+	paramPriors := make([]*Prior, len(names))
+	used := make(Priors, len(names))
+	for i, name := range names {
+		p := priors[name]
+		paramPriors[i] = &p
+		used[name] = p
+	}
+
+	start := m.fitLevenbergMarquardt(data, estimatedMinCP, estimatedMaxCP, maxPower)
+	if start == nil {
+		return nil, nil, errors.New("模型拟合失败")
+	}
+	trial := &CriticalPowerModel{modelType: m.modelType}
+	f.apply(trial, start)
+	rmse := math.Sqrt(trial.relativeMeanSquaredError(data))
+	start = append(slices.Clone(start), math.Log(max(rmse, DefaultNoiseScale/10)))
+
+	samples := m.mcmcSamples
+	if samples <= 0 {
+		samples = DefaultMCMCSamples
+	}
+	perChain := max(samples/mcmcChains, 1)
+
+	chains := make([][][]float64, mcmcChains)
+	accepted := 0
+	for c := range chains {
+		r := rand.New(rand.NewPCG(mcmcSeed, uint64(c)))
+		// 各条链从最小二乘结果附近的不同位置出发，便于用 R-hat 检查收敛
+		initial := make([]float64, d)
+		for i := range d - 1 {
+			initial[i] = max(start[i]*(1+0.1*r.NormFloat64()), lower[i])
+		}
+		initial[d-1] = start[d-1]
+		var n int
+		chains[c], n = runChain(trial, data, paramPriors, lower, initial, perChain, r)
+		accepted += n
+	}
+
+	posterior := &Posterior{
+		Chains:         mcmcChains,
+		Level:          DefaultConfidenceLevel,
+		AcceptanceRate: float64(accepted) / float64(mcmcChains*perChain*mcmcThin),
+		RHat:           make(map[string]float64, len(names)),
+		Priors:         used,
+	}
+	for i, name := range names {
+		posterior.RHat[name] = rHat(chains, i)
+	}
+
+	r := rand.New(rand.NewPCG(mcmcSeed, mcmcChains))
+	values := make(map[string][]float64)
+	means := make([]float64, d)
+	sigmaSum := 0.0
+	for _, chain := range chains {
+		for _, theta := range chain {
+			draw := &CriticalPowerModel{modelType: m.modelType}
+			f.apply(draw, theta[:d-1])
+			sigma := math.Exp(theta[d-1])
+			posterior.draws = append(posterior.draws, draw)
+			posterior.noise = append(posterior.noise, sigma*r.NormFloat64())
+			for name, value := range draw.allParams() {
+				values[name] = append(values[name], value)
+			}
+			for i := range means {
+				means[i] += theta[i]
+			}
+			sigmaSum += sigma
+		}
+	}
+	posterior.Samples = len(posterior.draws)
+	posterior.Sigma = sigmaSum / float64(posterior.Samples)
+	posterior.Means = make(map[string]float64, len(values))
+	posterior.Intervals = make(map[string]Interval, len(values))
+	for name, v := range values {
+		sum := 0.0
+		for _, x := range v {
+			sum += x
+		}
+		posterior.Means[name] = sum / float64(len(v))
+		posterior.Intervals[name] = percentileInterval(v, posterior.Level)
+	}
+	for i := range means {
+		means[i] /= float64(posterior.Samples)
+	}
+	return means[:d-1], posterior, nil
+}
+
+// runChain 运行一条自适应 Metropolis 链
+//
+// 预热的前半段使用对角提议分布，按接受率调整步长；后半段定期使用已有样本的协方差
+// （按 2.38²/d 缩放）作为提议分布并继续调整整体步长。预热结束后提议分布固定，
+// 保证保留的样本来自后验分布。返回保留的样本与预热后的接受次数。
+func runChain(trial *CriticalPowerModel, data []PowerTimePoint, priors []*Prior, lower, start []float64, samples int, r *rand.Rand) ([][]float64, int) {
+	d := len(start)
+	theta := slices.Clone(start)
+	lp := logPosterior(trial, data, priors, lower, theta)
+
+	// 初始步长：参数取当前值的 2% 与先验标准差的 10% 中较大者，ln σ 取 0.2
+	scale := make([]float64, d)
+	for i := range d - 1 {
+		scale[i] = 0.02 * max(math.Abs(theta[i]), 1)
+		if priors[i] != nil {
+			scale[i] = max(scale[i], 0.1*priors[i].SD)
+		}
+	}
+	scale[d-1] = 0.2
+	var chol [][]float64
+	factor := 1.0
+
+	candidate := make([]float64, d)
+	z := make([]float64, d)
+	var warmup [][]float64
+	windowAccepted, accepted := 0, 0
+	kept := make([][]float64, 0, samples)
+	for iter := 0; len(kept) < samples; iter++ {
+		for i := range z {
+			z[i] = r.NormFloat64()
+		}
+		for i := range d {
+			if chol == nil {
+				candidate[i] = theta[i] + factor*scale[i]*z[i]
+				continue
+			}
+			step := 0.0
+			for k := 0; k <= i; k++ {
+				step += chol[i][k] * z[k]
+			}
+			candidate[i] = theta[i] + factor*step
+		}
+
+		candidateLP := logPosterior(trial, data, priors, lower, candidate)
+		ok := math.Log(r.Float64()) < candidateLP-lp
+		if ok {
+			copy(theta, candidate)
+			lp = candidateLP
+		}
+
+		if iter < mcmcBurnIn {
+			if ok {
+				windowAccepted++
+			}
+			warmup = append(warmup, slices.Clone(theta))
+			// 每 100 次迭代调整步长，使接受率接近 0.234
+			if (iter+1)%100 == 0 {
+				factor *= math.Exp(float64(windowAccepted)/100 - 0.234)
+				windowAccepted = 0
+			}
+			// 预热过半后每 mcmcAdaptInterval 次迭代用后一半预热样本重新估计提议分布
+			if iter+1 >= mcmcBurnIn/2 && (iter+1)%mcmcAdaptInterval == 0 {
+				if l, ok := cholesky(sampleCovariance(warmup[len(warmup)/2:], 2.38*2.38/float64(d))); ok {
+					chol = l
+					factor = 1
+				}
+			}
+			continue
+		}
+		if ok {
+			accepted++
+		}
+		if (iter-mcmcBurnIn+1)%mcmcThin == 0 {
+			kept = append(kept, slices.Clone(theta))
+		}
+	}
+	return kept, accepted
+}
+
+// sampleCovariance 返回样本的协方差乘以 scale，对角线加上微小的正则项以保证正定
+func sampleCovariance(samples [][]float64, scale float64) [][]float64 {
+	d := len(samples[0])
+	mean := make([]float64, d)
+	for _, s := range samples {
+		for i, v := range s {
+			mean[i] += v / float64(len(samples))
+		}
+	}
+	cov := make([][]float64, d)
+	for i := range cov {
+		cov[i] = make([]float64, d)
+		for j := range d {
+			for _, s := range samples {
+				cov[i][j] += (s[i] - mean[i]) * (s[j] - mean[j])
+			}
+			cov[i][j] *= scale / float64(max(len(samples)-1, 1))
+		}
+		cov[i][i] += 1e-10 * max(math.Abs(mean[i]), 1)
+	}
+	return cov
+}
+
+// rHat 计算第 k 个参数的 Gelman–Rubin 潜在尺度缩减因子
+func rHat(chains [][][]float64, k int) float64 {
+	m := float64(len(chains))
+	n := float64(len(chains[0]))
+	if m < 2 || n < 2 {
+		return math.NaN()
+	}
+	means := make([]float64, len(chains))
+	grand := 0.0
+	within := 0.0
+	for c, chain := range chains {
+		for _, theta := range chain {
+			means[c] += theta[k]
+		}
+		means[c] /= n
+		grand += means[c] / m
+		variance := 0.0
+		for _, theta := range chain {
+			variance += (theta[k] - means[c]) * (theta[k] - means[c])
+		}
+		within += variance / (n - 1) / m
+	}
+	between := 0.0
+	for _, mean := range means {
+		between += (mean - grand) * (mean - grand)
+	}
+	between *= n / (m - 1)
+	if within == 0 {
+		return 1
+	}
+	return math.Sqrt(((n-1)/n*within + between/n) / within)
+}
@@ -0,0 +1,70 @@
+package criticalpower_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Equationzhao/power/criticalpower"
+)
+
+func TestBayesianFit(t *testing.T) {
+	// 三个点的三参数模型：最小二乘恰好插值，τ 与 W' 很难确定
+	truth := criticalpower.New(criticalpower.WithModel(criticalpower.ThreeParameter))
+	if err := truth.SetParams(map[string]float64{criticalpower.ParamCP: 260, criticalpower.ParamWprime: 18000, criticalpower.ParamTau: 12}); err != nil {
+		t.Fatal(err)
+	}
+	var data []criticalpower.PowerTimePoint
+	for i, d := range []float64{180, 420, 1200} {
+		noise := []float64{1.02, 0.98, 1.01}[i]
+		data = append(data, criticalpower.PowerTimePoint{Time: d, Power: truth.PredictPower(d) * noise})
+	}
+
+	fit := func() *criticalpower.CriticalPowerModel {
+		model := criticalpower.New(
+			criticalpower.WithModel(criticalpower.ThreeParameter),
+			criticalpower.WithFitter(criticalpower.Bayesian),
+			criticalpower.WithPriors(criticalpower.DefaultPriors(criticalpower.ThreeParameter, 75)),
+			criticalpower.WithMCMCSamples(2000),
+		)
+		if err := model.Fit(data); err != nil {
+			t.Fatalf("拟合失败: %v", err)
+		}
+		return model
+	}
+	model := fit()
+	p := model.Posterior
+	if p == nil || p.Samples != 2000 {
+		t.Fatalf("后验 = %+v", p)
+	}
+	if model.CP < 230 || model.CP > 290 || model.Tau < 0.5 || model.Tau > 45 {
+		t.Errorf("CP = %.1f, τ = %.1f", model.CP, model.Tau)
+	}
+	for name, rhat := range p.RHat {
+		if rhat > 1.1 {
+			t.Errorf("%s 的 R-hat = %.3f，链没有收敛", name, rhat)
+		}
+	}
+	if p.AcceptanceRate < 0.1 || p.AcceptanceRate > 0.6 {
+		t.Errorf("接受率 = %.2f", p.AcceptanceRate)
+	}
+	for _, name := range []string{criticalpower.ParamCP, criticalpower.ParamWprime, criticalpower.ParamTau} {
+		iv, mean := p.Intervals[name], p.Means[name]
+		if !(iv.Lower < mean && mean < iv.Upper) {
+			t.Errorf("%s 的均值 %.1f 不在可信区间 [%.1f, %.1f] 内", name, mean, iv.Lower, iv.Upper)
+		}
+	}
+
+	// 后验预测区间包含测试本身的误差，比曲线的可信区间更宽
+	credible, predictive := p.CredibleBand(300), p.PredictiveBand(300)
+	if predictive.Upper-predictive.Lower <= credible.Upper-credible.Lower {
+		t.Errorf("预测区间 %+v 不比可信区间 %+v 宽", predictive, credible)
+	}
+	if mean := p.PredictiveMean(300); math.Abs(mean-truth.PredictPower(300))/mean > 0.05 {
+		t.Errorf("5 分钟预测均值 = %.1f, 真实值 %.1f", mean, truth.PredictPower(300))
+	}
+
+	// 使用固定种子，结果可以复现
+	if again := fit(); again.CP != model.CP || again.Tau != model.Tau {
+		t.Errorf("两次拟合结果不同: %.4f/%.4f", model.CP, again.CP)
+	}
+}
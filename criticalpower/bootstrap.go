@@ -0,0 +1,165 @@
+package criticalpower
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+)
+
+const (
+	DefaultBootstrapSamples = 200  // 默认重抽样次数
+	DefaultConfidenceLevel  = 0.95 // 默认置信水平
+	bootstrapSeed           = 0xb007
+)
+
+// Interval 区间
+type Interval struct {
+	Lower float64
+	Upper float64
+}
+
+// Bootstrap 残差自助法的不确定性估计
+//
+// 以拟合曲线为基准，将相对残差重抽样后叠加到预测值上生成新的数据集，
+// 每个数据集使用 Levenberg–Marquardt 重新拟合，由重拟合参数的分位数得到置信区间。
+// 使用固定种子，相同的输入得到相同的区间。
+type Bootstrap struct {
+	Samples   int                 // 成功拟合的重抽样次数
+	Level     float64             // 置信水平
+	Intervals map[string]Interval // 参数的置信区间，包括 cp、wprime、pmax、tau 以及模型的其他自由参数
+
+	replicates []*CriticalPowerModel // 重拟合的模型
+	noise      []float64             // 每个重拟合模型对应的相对残差，用于计算预测区间
+}
+
+// WithBootstrap 拟合后使用 samples 次残差重抽样估计参数的置信区间
+// 重抽样使用 Levenberg–Marquardt 重新拟合，与最小二乘的拟合目标一致，
+// 不能用于贝叶斯拟合（后验分布已经给出可信区间）与包络拟合（锚点的残差接近 0，区间没有意义）
+func WithBootstrap(samples int) ModelOption {
+	return func(m *CriticalPowerModel) {
+		if samples <= 0 {
+			samples = DefaultBootstrapSamples
+		}
+		m.bootstrapSamples = samples
+	}
+}
+
+// bootstrap 对当前拟合结果进行残差自助法估计
+func (m *CriticalPowerModel) bootstrap(samples int, level float64) (*Bootstrap, error) {
+	data := m.validData()
+	f := m.form()
+	n, p := maximalCount(data), len(f.names())
+	// 数据点不多于参数个数时曲线恰好穿过所有点，残差全为 0，区间没有意义
+	if n <= p {
+		return nil, fmt.Errorf("自助法需要多于 %d 个最大努力数据点", p)
+	}
+
+	// 最大努力数据点的相对残差，按自由度修正以抵消拟合带来的低估
+	residuals := make([]float64, 0, n)
+	scale := math.Sqrt(float64(n) / float64(n-p))
+	for _, point := range data {
+		if point.Censored {
+			continue
+		}
+		predicted := m.PredictPower(point.Time)
+		residuals = append(residuals, (point.Power-predicted)/predicted*scale)
+	}
+
+	r := rand.New(rand.NewPCG(bootstrapSeed, bootstrapSeed))
+	b := &Bootstrap{Level: level}
+	resampled := make([]PowerTimePoint, len(data))
+	for range samples {
+		for i, point := range data {
+			// 删失点只是下界，保持原样
+			if point.Censored {
+				resampled[i] = point
+				continue
+			}
+			predicted := m.PredictPower(point.Time)
+			resampled[i] = PowerTimePoint{
+				Time:  point.Time,
+				Power: predicted * (1 + residuals[r.IntN(n)]),
+			}
+		}
+
+		replicate := &CriticalPowerModel{
+			modelType: m.modelType,
+			fitter:    LevenbergMarquardt,
+			Data:      slices.Clone(resampled),
+		}
+		if err := replicate.fit(); err != nil {
+			continue
+		}
+		b.replicates = append(b.replicates, replicate)
+		b.noise = append(b.noise, residuals[r.IntN(n)])
+	}
+
+	b.Samples = len(b.replicates)
+	if b.Samples == 0 {
+		return nil, errors.New("自助法重抽样全部拟合失败")
+	}
+
+	values := make(map[string][]float64)
+	for _, replicate := range b.replicates {
+		for name, value := range replicate.allParams() {
+			values[name] = append(values[name], value)
+		}
+	}
+	b.Intervals = make(map[string]Interval, len(values))
+	for name, v := range values {
+		b.Intervals[name] = percentileInterval(v, level)
+	}
+	return b, nil
+}
+
+// ConfidenceBand 返回时间 t 的预测功率曲线的置信区间
+func (b *Bootstrap) ConfidenceBand(t float64) Interval {
+	values := make([]float64, len(b.replicates))
+	for i, replicate := range b.replicates {
+		values[i] = replicate.PredictPower(t)
+	}
+	return percentileInterval(values, b.Level)
+}
+
+// PredictionBand 返回时间 t 的单次测试功率的预测区间，在置信区间的基础上包含了测试本身的误差
+func (b *Bootstrap) PredictionBand(t float64) Interval {
+	values := make([]float64, len(b.replicates))
+	for i, replicate := range b.replicates {
+		values[i] = replicate.PredictPower(t) * (1 + b.noise[i])
+	}
+	return percentileInterval(values, b.Level)
+}
+
+// allParams 返回自由参数以及 cp、wprime、pmax、tau
+func (m *CriticalPowerModel) allParams() map[string]float64 {
+	params := m.Params()
+	params[ParamCP] = m.CP
+	params[ParamWprime] = m.Wprime
+	params[ParamPmax] = m.Pmax
+	params[ParamTau] = m.Tau
+	return params
+}
+
+// percentileInterval 返回 values 中心 level 比例的分位数区间，values 会被排序
+func percentileInterval(values []float64, level float64) Interval {
+	slices.Sort(values)
+	alpha := (1 - level) / 2
+	return Interval{
+		Lower: quantile(values, alpha),
+		Upper: quantile(values, 1-alpha),
+	}
+}
+
+// quantile 使用线性插值计算已排序数据的分位数
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+	pos := q * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	frac := pos - float64(lo)
+	return sorted[lo]*(1-frac) + sorted[hi]*frac
+}
@@ -0,0 +1,76 @@
+package criticalpower_test
+
+import (
+	"testing"
+
+	"github.com/Equationzhao/power/criticalpower"
+)
+
+// TestBootstrap 测试自助法置信区间覆盖点估计且结果可复现
+func TestBootstrap(t *testing.T) {
+	normalData, _ := generateTestDataWithOutliers()
+
+	fit := func() *criticalpower.CriticalPowerModel {
+		model := criticalpower.New(criticalpower.WithFitter(criticalpower.LevenbergMarquardt), criticalpower.WithBootstrap(100))
+		if err := model.Fit(normalData); err != nil {
+			t.Fatalf("模型拟合失败: %v", err)
+		}
+		return model
+	}
+	model := fit()
+	b := model.Bootstrap
+	if b == nil || b.Samples == 0 {
+		t.Fatal("未计算自助法估计")
+	}
+
+	for name, value := range map[string]float64{
+		criticalpower.ParamCP:     model.CP,
+		criticalpower.ParamWprime: model.Wprime,
+		criticalpower.ParamTau:    model.Tau,
+		criticalpower.ParamPmax:   model.Pmax,
+	} {
+		ci, ok := b.Intervals[name]
+		if !ok {
+			t.Errorf("缺少 %s 的置信区间", name)
+			continue
+		}
+		t.Logf("%s: %.1f [%.1f, %.1f]", name, value, ci.Lower, ci.Upper)
+		if ci.Lower > value || ci.Upper < value || ci.Lower >= ci.Upper {
+			t.Errorf("%s 的置信区间 [%.1f, %.1f] 不包含点估计 %.1f", name, ci.Lower, ci.Upper, value)
+		}
+	}
+
+	confidence := b.ConfidenceBand(300)
+	prediction := b.PredictionBand(300)
+	if prediction.Upper-prediction.Lower < confidence.Upper-confidence.Lower {
+		t.Errorf("预测区间 %v 应宽于置信区间 %v", prediction, confidence)
+	}
+
+	if again := fit(); again.Bootstrap.Intervals[criticalpower.ParamCP] != b.Intervals[criticalpower.ParamCP] {
+		t.Error("相同输入得到不同的置信区间")
+	}
+}
+
+// TestBootstrapUnsupportedFitter 测试贝叶斯与包络拟合不支持自助法
+func TestBootstrapUnsupportedFitter(t *testing.T) {
+	normalData, _ := generateTestDataWithOutliers()
+	for _, fitter := range []criticalpower.Fitter{criticalpower.Bayesian, criticalpower.Envelope} {
+		model := criticalpower.New(criticalpower.WithFitter(fitter), criticalpower.WithBootstrap(50))
+		if err := model.Fit(normalData); err == nil {
+			t.Errorf("%s 拟合使用自助法时应返回错误", fitter)
+		}
+	}
+}
+
+// TestBootstrapTooFewPoints 测试数据点不多于参数个数时返回错误而不是宽度为 0 的区间
+func TestBootstrapTooFewPoints(t *testing.T) {
+	data := []criticalpower.PowerTimePoint{
+		{Time: 60, Power: 500},
+		{Time: 300, Power: 320},
+		{Time: 1200, Power: 270},
+	}
+	model := criticalpower.New(criticalpower.WithFitter(criticalpower.LevenbergMarquardt), criticalpower.WithBootstrap(50))
+	if err := model.Fit(data); err == nil {
+		t.Error("3 个数据点的三参数模型使用自助法时应返回错误")
+	}
+}
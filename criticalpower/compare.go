@@ -0,0 +1,101 @@
+package criticalpower
+
+import (
+	"cmp"
+	"errors"
+	"math"
+	"slices"
+)
+
+// Comparison 单个模型的比较结果
+type Comparison struct {
+	Model  Model   // 拟合后的模型
+	Err    error   // 拟合失败时的错误，此时其余字段无意义
+	K      int     // 参数个数，包括残差方差
+	RMSE   float64 // 均方根误差（瓦特）
+	AIC    float64 // 赤池信息准则
+	AICc   float64 // 小样本修正的 AIC，n - K - 1 <= 0 时为 +Inf
+	BIC    float64 // 贝叶斯信息准则
+	Weight float64 // Akaike 权重
+}
+
+// Compare 使用同一组数据拟合多个模型，并按信息准则从优到劣排序
+//
+// 信息准则基于相对残差（与拟合目标一致）计算：AIC = n × ln(SSR/n) + 2K，
+// 所有模型均在完整的 data 上计算残差，即使模型在拟合时排除了异常值。
+// Akaike 权重使用 AICc 计算，样本量不足以计算 AICc 时使用 AIC。
+// 拟合失败的模型排在最后，Err 不为空。
+func Compare(data []PowerTimePoint, models ...Model) ([]Comparison, error) {
+	if len(models) == 0 {
+		return nil, errors.New("没有需要比较的模型")
+	}
+	n := float64(len(data))
+
+	results := make([]Comparison, len(models))
+	for i, model := range models {
+		results[i].Model = model
+		if err := model.Fit(data); err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		var ssr, sse float64
+		for _, point := range data {
+			relativeErr := relativeResidual(model.PredictPower(point.Time), point)
+			err := relativeErr * point.Power
+			sse += err * err
+			ssr += relativeErr * relativeErr
+		}
+		k := float64(len(model.Params()) + 1)
+		logLikelihood := n * math.Log(math.Max(ssr/n, math.SmallestNonzeroFloat64))
+
+		c := &results[i]
+		c.K = int(k)
+		c.RMSE = math.Sqrt(sse / n)
+		c.AIC = logLikelihood + 2*k
+		c.BIC = logLikelihood + k*math.Log(n)
+		c.AICc = math.Inf(1)
+		if n-k-1 > 0 {
+			c.AICc = c.AIC + 2*k*(k+1)/(n-k-1)
+		}
+	}
+
+	useAICc := true
+	for _, c := range results {
+		if c.Err == nil && math.IsInf(c.AICc, 1) {
+			useAICc = false
+		}
+	}
+	criterion := func(c Comparison) float64 {
+		if c.Err != nil {
+			return math.Inf(1)
+		}
+		if useAICc {
+			return c.AICc
+		}
+		return c.AIC
+	}
+
+	best := math.Inf(1)
+	for _, c := range results {
+		best = math.Min(best, criterion(c))
+	}
+	if math.IsInf(best, 1) {
+		return results, errors.New("所有模型拟合失败")
+	}
+	var total float64
+	for i := range results {
+		if results[i].Err == nil {
+			results[i].Weight = math.Exp(-(criterion(results[i]) - best) / 2)
+			total += results[i].Weight
+		}
+	}
+	for i := range results {
+		results[i].Weight /= total
+	}
+
+	slices.SortStableFunc(results, func(a, b Comparison) int {
+		return cmp.Compare(criterion(a), criterion(b))
+	})
+	return results, nil
+}
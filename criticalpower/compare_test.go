@@ -0,0 +1,88 @@
+package criticalpower_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Equationzhao/power/criticalpower"
+)
+
+// TestCompare 测试模型比较能选出生成数据的模型
+func TestCompare(t *testing.T) {
+	cp, wprime, tau := 250.0, 18000.0, 20.0
+	times := []float64{3, 10, 20, 45, 90, 180, 300, 480, 720, 1200}
+	data := make([]criticalpower.PowerTimePoint, len(times))
+	for i, ti := range times {
+		noise := 1 + 0.005*math.Sin(ti)
+		data[i] = criticalpower.PowerTimePoint{Time: ti, Power: (wprime + cp*(ti+tau)) / (ti + tau) * noise}
+	}
+
+	models := make([]criticalpower.Model, 0, 4)
+	for _, modelType := range []criticalpower.ModelType{criticalpower.TwoParameter, criticalpower.ThreeParameter, criticalpower.Exponential, criticalpower.OmniDomain} {
+		models = append(models, criticalpower.New(criticalpower.WithModel(modelType), criticalpower.WithFitter(criticalpower.LevenbergMarquardt)))
+	}
+	results, err := criticalpower.Compare(data, models...)
+	if err != nil {
+		t.Fatalf("模型比较失败: %v", err)
+	}
+
+	var totalWeight float64
+	for _, c := range results {
+		if c.Err != nil {
+			t.Logf("%s: %v", c.Model.(*criticalpower.CriticalPowerModel).Type(), c.Err)
+			continue
+		}
+		t.Logf("%s: RMSE %.2f AIC %.1f AICc %.1f BIC %.1f 权重 %.3f",
+			c.Model.(*criticalpower.CriticalPowerModel).Type(), c.RMSE, c.AIC, c.AICc, c.BIC, c.Weight)
+		totalWeight += c.Weight
+	}
+	if math.Abs(totalWeight-1) > 1e-9 {
+		t.Errorf("Akaike 权重之和 = %f", totalWeight)
+	}
+	if best := results[0].Model.(*criticalpower.CriticalPowerModel).Type(); best != criticalpower.ThreeParameter {
+		t.Errorf("最优模型 = %s, 期望 %s", best, criticalpower.ThreeParameter)
+	}
+	if results[len(results)-1].Err == nil {
+		t.Error("缺少长时间数据的 OmPD 模型应拟合失败并排在最后")
+	}
+}
+
+// TestCompareBayesian 测试贝叶斯拟合的默认先验按每个候选模型的类型生成，覆盖的先验对所有模型生效
+func TestCompareBayesian(t *testing.T) {
+	data := []criticalpower.PowerTimePoint{{Time: 60, Power: 520}, {Time: 180, Power: 380}, {Time: 600, Power: 300}, {Time: 1200, Power: 275}}
+	override := criticalpower.Priors{criticalpower.ParamCP: {Mean: 270, SD: 30}}
+	options := []criticalpower.ModelOption{
+		criticalpower.WithFitter(criticalpower.Bayesian),
+		criticalpower.WithPriorMass(70),
+		criticalpower.WithPriors(override),
+		criticalpower.WithMCMCSamples(500),
+	}
+
+	modelTypes := []criticalpower.ModelType{criticalpower.TwoParameter, criticalpower.ThreeParameter, criticalpower.Exponential}
+	models := make([]criticalpower.Model, len(modelTypes))
+	for i, modelType := range modelTypes {
+		models[i] = criticalpower.New(append(options, criticalpower.WithModel(modelType))...)
+	}
+	results, err := criticalpower.Compare(data, models...)
+	if err != nil {
+		t.Fatalf("模型比较失败: %v", err)
+	}
+
+	for _, c := range results {
+		model := c.Model.(*criticalpower.CriticalPowerModel)
+		if c.Err != nil {
+			t.Fatalf("%s 拟合失败: %v", model.Type(), c.Err)
+		}
+		priors := model.Posterior.Priors
+		defaults := criticalpower.DefaultPriors(model.Type(), 70)
+		if len(priors) != len(model.Params()) {
+			t.Errorf("%s 的先验 %v 与自由参数 %v 不对应", model.Type(), priors, model.Params())
+		}
+		if priors[criticalpower.ParamCP] != override[criticalpower.ParamCP] {
+			t.Errorf("%s 的 CP 先验 = %+v, 期望覆盖为 %+v", model.Type(), priors[criticalpower.ParamCP], override[criticalpower.ParamCP])
+		}
+		if tau, ok := priors[criticalpower.ParamTau]; ok && tau != defaults[criticalpower.ParamTau] {
+			t.Errorf("%s 的 τ 先验 = %+v, 期望 %+v", model.Type(), tau, defaults[criticalpower.ParamTau])
+		}
+	}
+}
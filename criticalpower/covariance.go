@@ -0,0 +1,111 @@
+package criticalpower
+
+import "math"
+
+// PoorlyIdentifiedRSE 相对标准误差超过该值的参数视为数据无法很好地确定
+const PoorlyIdentifiedRSE = 0.5
+
+// Covariance 由拟合最优点的雅可比矩阵得到的参数协方差
+//
+// 拟合目标为相对残差平方和，协方差 = s² × (JᵀJ)⁻¹，其中 J 为相对残差对自由参数的雅可比矩阵，
+// s² = 残差平方和/(n - p)。该估计基于局部线性近似，数据点较少时应配合自助法使用。
+type Covariance struct {
+	Names       []string           // 参数名，顺序与矩阵的行列一致
+	Matrix      [][]float64        // 协方差矩阵
+	Correlation [][]float64        // 相关系数矩阵
+	StdErr      map[string]float64 // 标准误差
+}
+
+// covariance 计算当前拟合结果的参数协方差，数据点不多于参数个数或矩阵奇异时返回 nil
+func (m *CriticalPowerModel) covariance() *Covariance {
+	data := m.fitData()
+	f := m.form()
+	names := f.names()
+	n, p := len(data), len(names)
+	if n <= p {
+		return nil
+	}
+
+	trial := &CriticalPowerModel{modelType: m.modelType}
+	params := f.values(m)
+	residual := make([]float64, n)
+	ssr := relativeResiduals(trial, data, nil, params, residual)
+	jacobian := relativeJacobian(trial, data, nil, params)
+
+	jtj := make([][]float64, p)
+	for i := range p {
+		jtj[i] = make([]float64, p)
+		for k := range n {
+			for j := range p {
+				jtj[i][j] += jacobian[k][i] * jacobian[k][j]
+			}
+		}
+	}
+	inverse, ok := invert(jtj)
+	if !ok {
+		return nil
+	}
+
+	s2 := ssr / float64(n-p)
+	c := &Covariance{
+		Names:       names,
+		Matrix:      make([][]float64, p),
+		Correlation: make([][]float64, p),
+		StdErr:      make(map[string]float64, p),
+	}
+	for i := range p {
+		c.Matrix[i] = make([]float64, p)
+		for j := range p {
+			c.Matrix[i][j] = s2 * inverse[i][j]
+			if math.IsNaN(c.Matrix[i][j]) || math.IsInf(c.Matrix[i][j], 0) {
+				return nil
+			}
+		}
+	}
+	for i, name := range names {
+		c.StdErr[name] = math.Sqrt(math.Max(c.Matrix[i][i], 0))
+	}
+	for i := range p {
+		c.Correlation[i] = make([]float64, p)
+		for j := range p {
+			se := c.StdErr[names[i]] * c.StdErr[names[j]]
+			if se > 0 {
+				c.Correlation[i][j] = c.Matrix[i][j] / se
+			}
+		}
+	}
+	return c
+}
+
+// Corr 返回两个参数的相关系数，参数不存在时返回 0
+func (c *Covariance) Corr(a, b string) float64 {
+	i, j := -1, -1
+	for k, name := range c.Names {
+		if name == a {
+			i = k
+		}
+		if name == b {
+			j = k
+		}
+	}
+	if i < 0 || j < 0 {
+		return 0
+	}
+	return c.Correlation[i][j]
+}
+
+// PoorlyIdentified 返回相对标准误差超过 PoorlyIdentifiedRSE 的参数
+func (m *CriticalPowerModel) PoorlyIdentified() []string {
+	if m.Covariance == nil {
+		return nil
+	}
+	params := m.Params()
+	var names []string
+	for _, name := range m.Covariance.Names {
+		value := math.Abs(params[name])
+		if value == 0 || m.Covariance.StdErr[name]/value > PoorlyIdentifiedRSE {
+			names = append(names, name)
+		}
+	}
+	return names
+}
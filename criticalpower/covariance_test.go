@@ -0,0 +1,45 @@
+package criticalpower_test
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/Equationzhao/power/criticalpower"
+)
+
+// TestCovariance 测试协方差的标准误差与自助法区间大致一致，且能识别无法确定的参数
+func TestCovariance(t *testing.T) {
+	normalData, _ := generateTestDataWithOutliers()
+	model := criticalpower.New(criticalpower.WithFitter(criticalpower.LevenbergMarquardt), criticalpower.WithBootstrap(200))
+	if err := model.Fit(normalData); err != nil {
+		t.Fatalf("模型拟合失败: %v", err)
+	}
+	c := model.Covariance
+	if c == nil {
+		t.Fatal("未计算协方差")
+	}
+	for i, name := range c.Names {
+		if math.Abs(c.Correlation[i][i]-1) > 1e-9 {
+			t.Errorf("%s 的自相关系数 = %f", name, c.Correlation[i][i])
+		}
+		ci := model.Bootstrap.Intervals[name]
+		t.Logf("%s: 标准误差 %.2f, 自助法区间宽度 %.2f", name, c.StdErr[name], ci.Upper-ci.Lower)
+		// 95% 区间宽度约为 4 个标准误差
+		if ratio := (ci.Upper - ci.Lower) / (4 * c.StdErr[name]); ratio < 0.3 || ratio > 3 {
+			t.Errorf("%s 的标准误差与自助法区间差异过大: %.2f", name, ratio)
+		}
+	}
+	t.Logf("CP 与 W' 的相关系数: %.3f", c.Corr(criticalpower.ParamCP, criticalpower.ParamWprime))
+
+	// 只有短时间的数据点时 CP 无法确定
+	short := []criticalpower.PowerTimePoint{{Time: 1, Power: 900}, {Time: 5, Power: 820}, {Time: 10, Power: 760}, {Time: 15, Power: 700}}
+	model = criticalpower.New(criticalpower.WithFitter(criticalpower.LevenbergMarquardt))
+	if err := model.Fit(short); err != nil {
+		t.Fatalf("模型拟合失败: %v", err)
+	}
+	t.Logf("短时间数据: %v, 无法确定的参数: %v", model.Params(), model.PoorlyIdentified())
+	if !slices.Contains(model.PoorlyIdentified(), criticalpower.ParamCP) {
+		t.Error("只有短时间数据时 CP 应无法确定")
+	}
+}
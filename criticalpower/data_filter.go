@@ -12,8 +12,7 @@ func (m *CriticalPowerModel) detectOutliers(threshold float64) {
 	// 计算每个点的残差
 	residuals := make([]float64, len(data))
 	for i, point := range data {
-		predicted := (m.Wprime + m.CP*(point.Time+m.Tau)) / (point.Time + m.Tau)
-		residuals[i] = math.Abs(predicted-point.Power) / point.Power // 相对残差
+		residuals[i] = math.Abs(relativeResidual(m.PredictPower(point.Time), point)) // 相对残差
 	}
 
 	// 计算残差的四分位值
@@ -95,24 +94,35 @@ func (m *CriticalPowerModel) enforcePowerTimeConsistency() {
 		return
 	}
 
-	filtered := make([]PowerTimePoint, 0, len(data))
-	filtered = append(filtered, data[0])
+	// 只检查最大努力的数据点，删失点低于曲线是正常的
+	type indexed struct {
+		index int
+		point PowerTimePoint
+	}
+	filtered := make([]indexed, 0, len(data))
 
-	for i := 1; i < len(data); i++ {
+	for i := 0; i < len(data); i++ {
 		current := data[i]
+		if current.Censored {
+			continue
+		}
+		if len(filtered) == 0 {
+			filtered = append(filtered, indexed{i, current})
+			continue
+		}
 
-		if current.Power > filtered[len(filtered)-1].Power {
-			m.Outliers[i-1] = struct{}{}
+		if current.Power > filtered[len(filtered)-1].point.Power {
+			m.Outliers[filtered[len(filtered)-1].index] = struct{}{}
 			filtered = filtered[:len(filtered)-1]
 
-			if len(filtered) == 0 || current.Power <= filtered[len(filtered)-1].Power {
-				filtered = append(filtered, current)
+			if len(filtered) == 0 || current.Power <= filtered[len(filtered)-1].point.Power {
+				filtered = append(filtered, indexed{i, current})
 			} else {
 				m.Outliers[i] = struct{}{}
 				i--
 			}
 		} else {
-			filtered = append(filtered, current)
+			filtered = append(filtered, indexed{i, current})
 		}
 	}
 }
@@ -125,6 +135,9 @@ func (m *CriticalPowerModel) handleDataJump() {
 		m.Outliers = make(map[int]struct{})
 	}
 	for i := 1; i < len(data); i++ {
+		if data[i].Censored || data[i-1].Censored {
+			continue
+		}
 		if math.Abs(data[i].Time-data[i-1].Time)/data[i-1].Time < 0.2 &&
 			math.Abs(data[i].Power-data[i-1].Power)/data[i-1].Power > 0.2 {
 			m.Outliers[i] = struct{}{}
@@ -138,6 +151,10 @@ func (m *CriticalPowerModel) detectNonMaximalEffort() (count int) {
 		m.Outliers = make(map[int]struct{})
 	}
 	for i, point := range data {
+		// 删失点已经按下界处理，不需要排除
+		if point.Censored {
+			continue
+		}
 		expectedPower := m.PredictPower(point.Time)
 		actualPower := point.Power
 
@@ -0,0 +1,180 @@
+package criticalpower
+
+import (
+	"math"
+	"slices"
+)
+
+const (
+	envelopeTolerance  = 0.02 // 与曲线的相对差距不超过该值的前沿点作为锚点
+	envelopeIterations = 10   // 选择锚点的最大迭代次数
+)
+
+// envelopePenalties 约束曲线不低于数据点的罚函数权重，逐步增大使越界趋于 0
+var envelopePenalties = []float64{10, 100, 1000, 10000}
+
+// fitEnvelope 上包络拟合，返回参数以及锚点在 data 中的下标
+//
+// 与 GoldenCheetah 的 extended CP 类似，一个赛季的平均最大功率大多不是全力的，
+// 最小二乘会让曲线穿过数据的中间。包络拟合先取帕累托前沿（没有更长且功率更高的点），
+// 在曲线不低于所有数据点的约束下只对锚点最小化相对误差，再把与新曲线足够接近的前沿点作为锚点，
+// 重复直到锚点不再变化。
+func (m *CriticalPowerModel) fitEnvelope(data []PowerTimePoint, estimatedMinCP, estimatedMaxCP, maxPower float64) ([]float64, []int) {
+	f := m.form()
+	p := len(f.names())
+
+	front := paretoFront(data)
+	if len(front) < p {
+		front = front[:0]
+		for i, point := range data {
+			if !point.Censored {
+				front = append(front, i)
+			}
+		}
+	}
+
+	// 从普通最小二乘的结果出发
+	params := m.fitLevenbergMarquardt(data, estimatedMinCP, estimatedMaxCP, maxPower)
+	if params == nil {
+		return nil, nil
+	}
+	trial := &CriticalPowerModel{modelType: m.modelType}
+	anchors := front
+	for i := range envelopeIterations {
+		params = envelopeFit(trial, data, anchors, params)
+		f.apply(trial, params)
+		next := selectAnchors(trial, data, front, p)
+		if i == envelopeIterations-1 || slices.Equal(next, anchors) {
+			break
+		}
+		anchors = next
+	}
+	return params, anchors
+}
+
+// envelopeFit 在曲线不低于 data 中所有点的约束下对锚点拟合
+func envelopeFit(trial *CriticalPowerModel, data []PowerTimePoint, anchors []int, initial []float64) []float64 {
+	f := trial.form()
+
+	// 锚点正常计算残差，所有点再作为删失点加入，曲线低于数据点时受到惩罚
+	points := make([]PowerTimePoint, 0, len(anchors)+len(data))
+	for _, i := range anchors {
+		points = append(points, data[i])
+	}
+	for _, point := range data {
+		point.Censored = true
+		points = append(points, point)
+	}
+
+	params := initial
+	weights := make([]float64, len(points))
+	for _, penalty := range envelopePenalties {
+		for i := range weights {
+			weights[i] = penalty
+			if i < len(anchors) {
+				weights[i] = 1
+			}
+		}
+		params = levenbergMarquardt(trial, points, weights, params)
+	}
+
+	// 罚函数只能让越界趋于 0，最后按最大越界比例整体放大曲线，使其严格不低于所有数据点
+	f.apply(trial, params)
+	lift := 1.0
+	for _, point := range data {
+		lift = math.Max(lift, point.Power/trial.PredictPower(point.Time))
+	}
+	return scaleParams(f, params, lift)
+}
+
+// selectAnchors 返回与曲线的相对差距不超过 envelopeTolerance 的前沿点，不足 p 个时取差距最小的 p 个
+func selectAnchors(trial *CriticalPowerModel, data []PowerTimePoint, front []int, p int) []int {
+	gaps := make(map[int]float64, len(front))
+	var anchors []int
+	for _, i := range front {
+		gaps[i] = trial.PredictPower(data[i].Time)/data[i].Power - 1
+		if gaps[i] <= envelopeTolerance {
+			anchors = append(anchors, i)
+		}
+	}
+	if len(anchors) < p {
+		anchors = slices.Clone(front)
+		slices.SortStableFunc(anchors, func(a, b int) int {
+			switch {
+			case gaps[a] < gaps[b]:
+				return -1
+			case gaps[a] > gaps[b]:
+				return 1
+			}
+			return 0
+		})
+		anchors = anchors[:p]
+		slices.Sort(anchors)
+	}
+	return anchors
+}
+
+// paretoFront 返回最大努力数据点中的帕累托前沿的下标（升序），即不存在时间不短且功率不低的其他点
+// 完全相同的点只保留第一个
+func paretoFront(data []PowerTimePoint) []int {
+	order := make([]int, 0, len(data))
+	for i, point := range data {
+		if !point.Censored {
+			order = append(order, i)
+		}
+	}
+	// 时间从长到短，同一时间功率从高到低
+	slices.SortStableFunc(order, func(a, b int) int {
+		pa, pb := data[a], data[b]
+		switch {
+		case pa.Time != pb.Time:
+			if pa.Time > pb.Time {
+				return -1
+			}
+			return 1
+		case pa.Power > pb.Power:
+			return -1
+		case pa.Power < pb.Power:
+			return 1
+		}
+		return 0
+	})
+
+	var front []int
+	best := math.Inf(-1)
+	for _, i := range order {
+		if data[i].Power > best {
+			front = append(front, i)
+			best = data[i].Power
+		}
+	}
+	slices.Sort(front)
+	return front
+}
+
+// fitData 返回参与拟合的数据，包络拟合只使用锚点
+func (m *CriticalPowerModel) fitData() []PowerTimePoint {
+	if m.Anchors == nil {
+		return m.validData()
+	}
+	data := make([]PowerTimePoint, len(m.Anchors))
+	for i, index := range m.Anchors {
+		data[i] = m.Data[index]
+	}
+	return data
+}
+
+// dataIndices 将 validData 中的下标转换为 Data 中的下标
+func (m *CriticalPowerModel) dataIndices(valid []int) []int {
+	kept := make([]int, 0, len(m.Data))
+	for i := range m.Data {
+		if _, ok := m.Outliers[i]; !ok {
+			kept = append(kept, i)
+		}
+	}
+	indices := make([]int, len(valid))
+	for i, v := range valid {
+		indices[i] = kept[v]
+	}
+	return indices
+}
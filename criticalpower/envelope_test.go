@@ -0,0 +1,67 @@
+package criticalpower_test
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/Equationzhao/power/criticalpower"
+)
+
+// seasonCloud 生成一个赛季的平均最大功率：每个时长有多次次最大努力，少数时长有一次全力
+func seasonCloud(cp, wprime, tau float64) []criticalpower.PowerTimePoint {
+	r := rand.New(rand.NewPCG(1, 2))
+	truth := func(t float64) float64 { return (wprime + cp*(t+tau)) / (t + tau) }
+	var data []criticalpower.PowerTimePoint
+	for _, t := range []float64{5, 10, 20, 30, 60, 120, 180, 300, 420, 600, 900, 1200, 1800, 2400, 3600} {
+		for range 8 {
+			data = append(data, criticalpower.PowerTimePoint{Time: t, Power: truth(t) * (0.7 + 0.27*r.Float64())})
+		}
+	}
+	for _, t := range []float64{10, 180, 600, 1200, 3600} {
+		data = append(data, criticalpower.PowerTimePoint{Time: t, Power: truth(t)})
+	}
+	return data
+}
+
+// TestEnvelopeFit 测试上包络拟合：曲线不低于所有点，经过全力的点，而最小二乘落在数据中间
+func TestEnvelopeFit(t *testing.T) {
+	cp, wprime := 260.0, 18000.0
+	data := seasonCloud(cp, wprime, 8)
+
+	envelope := criticalpower.New(criticalpower.WithFitter(criticalpower.Envelope))
+	if err := envelope.Fit(data); err != nil {
+		t.Fatalf("模型拟合失败: %v", err)
+	}
+	lm := criticalpower.New(criticalpower.WithFitter(criticalpower.LevenbergMarquardt))
+	if err := lm.Fit(data); err != nil {
+		t.Fatalf("模型拟合失败: %v", err)
+	}
+	t.Logf("envelope: %v, anchors %v; lm: %v", envelope.Params(), envelope.Anchors, lm.Params())
+
+	for _, point := range data {
+		if predicted := envelope.PredictPower(point.Time); predicted < point.Power*(1-1e-9) {
+			t.Errorf("%.0f 秒的曲线 %.1f 低于数据点 %.1f", point.Time, predicted, point.Power)
+		}
+	}
+	if math.Abs(envelope.CP-cp)/cp > 0.03 || math.Abs(envelope.Wprime-wprime)/wprime > 0.1 {
+		t.Errorf("包络拟合参数 %v 与真实值 cp=%.0f wprime=%.0f 相差过大", envelope.Params(), cp, wprime)
+	}
+	if lm.CP > cp*0.95 {
+		t.Errorf("最小二乘的 CP %.1f 应明显低于包络", lm.CP)
+	}
+
+	if len(envelope.Anchors) < 3 {
+		t.Fatalf("锚点数量 %d 少于参数个数", len(envelope.Anchors))
+	}
+	for _, i := range envelope.Anchors {
+		point := data[i]
+		if gap := envelope.PredictPower(point.Time)/point.Power - 1; gap > 0.05 {
+			t.Errorf("锚点 %v 与曲线相差 %.1f%%", point, gap*100)
+		}
+	}
+
+	if lm.Anchors != nil {
+		t.Error("最小二乘拟合不应返回锚点")
+	}
+}
@@ -0,0 +1,188 @@
+package criticalpower
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+)
+
+// Fitter 拟合算法
+type Fitter string
+
+const (
+	Annealing          Fitter = "annealing" // 随机初始值的模拟退火，适合病态数据
+	LevenbergMarquardt Fitter = "lm"        // 带下界约束的 Levenberg–Marquardt 非线性最小二乘，结果确定
+	Bayesian           Fitter = "bayes"     // 带先验的贝叶斯拟合，MCMC 采样后验分布，适合数据点很少的情况
+	Envelope           Fitter = "envelope"  // 上包络拟合，曲线不低于所有数据点，适合整个赛季的平均最大功率
+)
+
+// DefaultFitter 默认拟合算法
+const DefaultFitter = Annealing
+
+// ParseFitter 解析拟合算法，空字符串返回默认算法
+func ParseFitter(s string) (Fitter, error) {
+	switch f := Fitter(s); f {
+	case "":
+		return DefaultFitter, nil
+	case Annealing, LevenbergMarquardt, Bayesian, Envelope:
+		return f, nil
+	default:
+		return "", errors.New("未知的拟合算法: " + s)
+	}
+}
+
+const (
+	lmStarts        = 16    // 初始值个数
+	lmMaxIterations = 200   // 单次优化的最大迭代次数
+	lmTolerance     = 1e-12 // 误差的相对变化小于该值时认为收敛
+	lmSeed          = 0x5eed
+)
+
+// fitLevenbergMarquardt 从固定种子生成的多个初始值出发运行 Levenberg–Marquardt，
+// 返回相对误差最小的参数。相同的输入总是得到相同的结果。
+func (m *CriticalPowerModel) fitLevenbergMarquardt(data []PowerTimePoint, estimatedMinCP, estimatedMaxCP, maxPower float64) []float64 {
+	f := m.form()
+	trial := &CriticalPowerModel{modelType: m.modelType}
+	r := rand.New(rand.NewPCG(lmSeed, lmSeed))
+
+	var bestParams []float64
+	bestError := math.Inf(1)
+	for range lmStarts {
+		initial := f.initial(r, estimatedMinCP, estimatedMaxCP, maxPower)
+		params := levenbergMarquardt(trial, data, nil, initial)
+		f.apply(trial, params)
+		mrse := trial.relativeMeanSquaredError(data)
+		if mrse < bestError {
+			bestError = mrse
+			bestParams = params
+		}
+	}
+	return bestParams
+}
+
+// levenbergMarquardt 最小化相对残差平方和，参数超出下界时投影回边界
+// weights 为每个数据点残差的权重，nil 表示权重都为 1
+func levenbergMarquardt(trial *CriticalPowerModel, data []PowerTimePoint, weights, initial []float64) []float64 {
+	f := trial.form()
+	lower := f.lower()
+	n := len(initial)
+
+	params := make([]float64, n)
+	for i := range params {
+		params[i] = max(initial[i], lower[i])
+	}
+	residual := make([]float64, len(data))
+	candidate := make([]float64, n)
+	candidateResidual := make([]float64, len(data))
+
+	cost := relativeResiduals(trial, data, weights, params, residual)
+	lambda := 1e-3
+	for range lmMaxIterations {
+		jacobian := relativeJacobian(trial, data, weights, params)
+
+		// 构造正规方程 JᵀJ 与 Jᵀr
+		jtj := make([][]float64, n)
+		jtr := make([]float64, n)
+		for i := range n {
+			jtj[i] = make([]float64, n)
+			for k := range data {
+				jtr[i] += jacobian[k][i] * residual[k]
+				for j := range n {
+					jtj[i][j] += jacobian[k][i] * jacobian[k][j]
+				}
+			}
+		}
+
+		improved := false
+		for !improved && lambda < 1e12 {
+			a := make([][]float64, n)
+			b := make([]float64, n)
+			for i := range n {
+				a[i] = append([]float64(nil), jtj[i]...)
+				a[i][i] += lambda * max(jtj[i][i], 1e-12)
+				b[i] = -jtr[i]
+			}
+			delta, ok := solveLinear(a, b)
+			if !ok {
+				lambda *= 10
+				continue
+			}
+			for i := range n {
+				candidate[i] = max(params[i]+delta[i], lower[i])
+			}
+			newCost := relativeResiduals(trial, data, weights, candidate, candidateResidual)
+			if newCost < cost {
+				improved = true
+				converged := (cost-newCost)/math.Max(cost, 1e-300) < lmTolerance
+				copy(params, candidate)
+				copy(residual, candidateResidual)
+				cost = newCost
+				lambda = math.Max(lambda/10, 1e-12)
+				if converged {
+					return params
+				}
+			} else {
+				lambda *= 10
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+	return params
+}
+
+// relativeResiduals 计算加权的相对残差 (P(t) - y)/y 并返回其平方和，删失点只计曲线低于该点的部分
+func relativeResiduals(trial *CriticalPowerModel, data []PowerTimePoint, weights, params, residual []float64) float64 {
+	trial.form().apply(trial, params)
+	var sum float64
+	for i, point := range data {
+		residual[i] = relativeResidual(trial.PredictPower(point.Time), point)
+		if weights != nil {
+			residual[i] *= weights[i]
+		}
+		sum += residual[i] * residual[i]
+	}
+	return sum
+}
+
+// relativeJacobian 使用中心差分计算相对残差对参数的雅可比矩阵，行对应数据点
+// 曲线已经高于删失点时该点的残差恒为 0，对应的行为 0
+func relativeJacobian(trial *CriticalPowerModel, data []PowerTimePoint, weights, params []float64) [][]float64 {
+	f := trial.form()
+	jacobian := make([][]float64, len(data))
+	for i := range jacobian {
+		jacobian[i] = make([]float64, len(params))
+	}
+
+	shifted := append([]float64(nil), params...)
+	for j := range params {
+		h := 1e-6 * math.Max(math.Abs(params[j]), 1)
+
+		shifted[j] = params[j] + h
+		f.apply(trial, shifted)
+		for i, point := range data {
+			jacobian[i][j] = trial.PredictPower(point.Time) / point.Power
+		}
+
+		shifted[j] = params[j] - h
+		f.apply(trial, shifted)
+		for i, point := range data {
+			jacobian[i][j] = (jacobian[i][j] - trial.PredictPower(point.Time)/point.Power) / (2 * h)
+		}
+
+		shifted[j] = params[j]
+	}
+	f.apply(trial, params)
+	for i, point := range data {
+		if point.Censored && relativeResidual(trial.PredictPower(point.Time), point) == 0 {
+			clear(jacobian[i])
+		}
+		if weights != nil {
+			for j := range jacobian[i] {
+				jacobian[i][j] *= weights[i]
+			}
+		}
+	}
+	return jacobian
+}
@@ -0,0 +1,105 @@
+package criticalpower_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Equationzhao/power/criticalpower"
+)
+
+// TestLevenbergMarquardt 测试 Levenberg–Marquardt 拟合的准确性与确定性
+func TestLevenbergMarquardt(t *testing.T) {
+	normalData, _ := generateTestDataWithOutliers()
+
+	for _, modelType := range []criticalpower.ModelType{criticalpower.TwoParameter, criticalpower.ThreeParameter, criticalpower.Exponential} {
+		t.Run(string(modelType), func(t *testing.T) {
+			lm := criticalpower.New(criticalpower.WithModel(modelType), criticalpower.WithFitter(criticalpower.LevenbergMarquardt))
+			if err := lm.Fit(normalData); err != nil {
+				t.Fatalf("模型拟合失败: %v", err)
+			}
+			again := criticalpower.New(criticalpower.WithModel(modelType), criticalpower.WithFitter(criticalpower.LevenbergMarquardt))
+			if err := again.Fit(normalData); err != nil {
+				t.Fatalf("模型拟合失败: %v", err)
+			}
+			if lm.CP != again.CP || lm.Wprime != again.Wprime || lm.Tau != again.Tau {
+				t.Errorf("相同输入得到不同结果: %+v, %+v", lm.Params(), again.Params())
+			}
+
+			t.Logf("LM: %v, RMSE %.2f", lm.Params(), lm.RMSE)
+		})
+	}
+}
+
+// TestLevenbergMarquardtAccuracy 测试 Levenberg–Marquardt 能从理想数据中还原三参数模型
+func TestLevenbergMarquardtAccuracy(t *testing.T) {
+	cp, wprime, tau := 230.0, 20000.0, 5.0
+	times := []float64{1, 5, 10, 30, 60, 180, 300, 600, 1200, 1800}
+	data := make([]criticalpower.PowerTimePoint, len(times))
+	for i, ti := range times {
+		data[i] = criticalpower.PowerTimePoint{Time: ti, Power: (wprime + cp*(ti+tau)) / (ti + tau)}
+	}
+
+	model := criticalpower.New(criticalpower.WithFitter(criticalpower.LevenbergMarquardt))
+	if err := model.Fit(data); err != nil {
+		t.Fatalf("模型拟合失败: %v", err)
+	}
+	if math.Abs(model.CP-cp) > 0.01 || math.Abs(model.Wprime-wprime) > 1 || math.Abs(model.Tau-tau) > 0.01 {
+		t.Errorf("拟合参数 %v 与真实值 cp=%.0f wprime=%.0f tau=%.0f 不符", model.Params(), cp, wprime, tau)
+	}
+}
+
+func TestParseFitter(t *testing.T) {
+	if f, err := criticalpower.ParseFitter(""); err != nil || f != criticalpower.DefaultFitter {
+		t.Errorf("空字符串应返回默认算法，得到 %q, %v", f, err)
+	}
+	if _, err := criticalpower.ParseFitter("unknown"); err == nil {
+		t.Error("未知拟合算法应返回错误")
+	}
+}
+
+// TestCensoredFit 测试删失点只作为下界：低于曲线的训练数据不会拉低 CP，高于曲线的会抬高曲线
+func TestCensoredFit(t *testing.T) {
+	cp, wprime, tau := 260.0, 20000.0, 10.0
+	power := func(ti float64) float64 { return (wprime + cp*(ti+tau)) / (ti + tau) }
+
+	var data []criticalpower.PowerTimePoint
+	for _, ti := range []float64{60, 180, 300, 720, 1200} {
+		data = append(data, criticalpower.PowerTimePoint{Time: ti, Power: power(ti)})
+	}
+	// 训练中的长时间骑行只有曲线的 80%~90%
+	for i, ti := range []float64{1800, 2400, 3600, 5400, 7200} {
+		data = append(data, criticalpower.PowerTimePoint{Time: ti, Power: power(ti) * (0.8 + 0.02*float64(i)), Censored: true})
+	}
+
+	fit := func(data []criticalpower.PowerTimePoint) *criticalpower.CriticalPowerModel {
+		model := criticalpower.New(criticalpower.WithModel(criticalpower.ThreeParameter), criticalpower.WithFitter(criticalpower.LevenbergMarquardt))
+		if err := model.Fit(data); err != nil {
+			t.Fatalf("模型拟合失败: %v", err)
+		}
+		return model
+	}
+
+	censored := fit(data)
+	if math.Abs(censored.CP-cp) > 1 {
+		t.Errorf("删失拟合 CP = %.1f, 期望 %.0f", censored.CP, cp)
+	}
+
+	// 当作最大努力时 CP 被明显拉低
+	maximal := make([]criticalpower.PowerTimePoint, len(data))
+	for i, point := range data {
+		maximal[i] = criticalpower.PowerTimePoint{Time: point.Time, Power: point.Power}
+	}
+	if biased := fit(maximal); biased.CP > cp-10 {
+		t.Errorf("未标记删失时 CP = %.1f，应明显低于 %.0f", biased.CP, cp)
+	}
+
+	// 高于曲线的删失点说明真实能力更高，曲线被抬高
+	above := append(data, criticalpower.PowerTimePoint{Time: 3000, Power: 290, Censored: true})
+	if model := fit(above); model.PredictPower(3000) < censored.PredictPower(3000)+5 {
+		t.Errorf("3000 秒的预测功率 %.1f 没有被删失点 290 抬高", model.PredictPower(3000))
+	}
+
+	if err := criticalpower.New().Fit(data[5:]); err == nil {
+		t.Error("只有删失点时应返回错误")
+	}
+}
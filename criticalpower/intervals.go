@@ -0,0 +1,104 @@
+package criticalpower
+
+import "errors"
+
+// IntervalSession 结构化间歇训练：Reps 组 WorkDuration 秒 WorkPower 瓦，组间以 RestPower 恢复 RestDuration 秒
+type IntervalSession struct {
+	Reps         int
+	WorkDuration int // 秒
+	WorkPower    float64
+	RestDuration int // 秒
+	RestPower    float64
+}
+
+// IntervalResult 间歇训练的 W'bal 模拟结果
+type IntervalResult struct {
+	Feasible      bool      // 是否能完成全部组数
+	CompletedReps int       // 完整完成的组数
+	ExhaustedAt   int       // W'bal 首次降到 0 的时间（秒），未耗尽时为 -1
+	ExhaustedRep  int       // 耗尽时所在的组，从 1 开始，未耗尽时为 0
+	Balance       []float64 // 每秒的 W'bal
+	MinBalance    float64
+	MaxWorkPower  float64 // 保持训练时长与恢复不变，能完成全部组数的最大训练功率
+}
+
+// intervalIterations 二分查找最大训练功率的次数
+const intervalIterations = 50
+
+// Power 返回间歇训练的 1 Hz 功率流，最后一组之后没有恢复
+func (s IntervalSession) Power() []float64 {
+	if s.Reps <= 0 {
+		return nil
+	}
+	power := make([]float64, 0, s.Reps*(s.WorkDuration+s.RestDuration)-s.RestDuration)
+	for rep := range s.Reps {
+		if rep > 0 {
+			for range s.RestDuration {
+				power = append(power, s.RestPower)
+			}
+		}
+		for range s.WorkDuration {
+			power = append(power, s.WorkPower)
+		}
+	}
+	return power
+}
+
+// SimulateIntervals 使用 W'bal 模拟间歇训练，返回可以完成的组数、耗尽的位置以及能完成全部组数的最大训练功率
+func SimulateIntervals(cp, wprime float64, session IntervalSession, method WbalMethod) (*IntervalResult, error) {
+	if session.Reps <= 0 || session.WorkDuration <= 0 {
+		return nil, errors.New("组数与训练时长必须大于0")
+	}
+	if session.WorkPower <= 0 {
+		return nil, errors.New("训练功率必须大于0")
+	}
+	if session.RestDuration < 0 || session.RestPower < 0 {
+		return nil, errors.New("恢复时长与功率不能为负")
+	}
+
+	wbal, err := Wbal(session.Power(), cp, wprime, method)
+	if err != nil {
+		return nil, err
+	}
+	result := &IntervalResult{
+		ExhaustedAt: wbal.ExhaustedAt,
+		Balance:     wbal.Balance,
+		MinBalance:  wbal.Min,
+	}
+	period := session.WorkDuration + session.RestDuration
+	if wbal.ExhaustedAt < 0 {
+		result.Feasible = true
+		result.CompletedReps = session.Reps
+	} else {
+		// 耗尽发生在训练中时该组未完成，发生在恢复中时该组已完成、下一组无法完成
+		rep, offset := wbal.ExhaustedAt/period, wbal.ExhaustedAt%period
+		result.CompletedReps = rep
+		if offset >= session.WorkDuration {
+			result.CompletedReps++
+		}
+		result.ExhaustedRep = result.CompletedReps + 1
+	}
+
+	// 训练功率为 CP 时不消耗 W'，一组即耗尽 W' 的功率是上界
+	low, high := cp, cp+wprime/float64(session.WorkDuration)
+	trial := session
+	for range intervalIterations {
+		trial.WorkPower = (low + high) / 2
+		wbal, err := Wbal(trial.Power(), cp, wprime, method)
+		if err != nil {
+			return nil, err
+		}
+		if wbal.ExhaustedAt < 0 {
+			low = trial.WorkPower
+		} else {
+			high = trial.WorkPower
+		}
+	}
+	result.MaxWorkPower = low
+	return result, nil
+}
+
+// SimulateIntervals 使用模型的 CP 与 W' 模拟间歇训练
+func (m *CriticalPowerModel) SimulateIntervals(session IntervalSession, method WbalMethod) (*IntervalResult, error) {
+	return SimulateIntervals(m.CP, m.Wprime, session, method)
+}
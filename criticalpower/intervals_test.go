@@ -0,0 +1,58 @@
+package criticalpower_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Equationzhao/power/criticalpower"
+)
+
+func TestSimulateIntervals(t *testing.T) {
+	cp, wprime := 250.0, 20000.0
+	// 6 × 3 分钟 140% CP，组间 3 分钟 50% CP
+	session := criticalpower.IntervalSession{
+		Reps:         6,
+		WorkDuration: 180,
+		WorkPower:    1.4 * cp,
+		RestDuration: 180,
+		RestPower:    0.5 * cp,
+	}
+
+	result, err := criticalpower.SimulateIntervals(cp, wprime, session, criticalpower.WbalDifferential)
+	if err != nil {
+		t.Fatalf("计算失败: %v", err)
+	}
+	t.Logf("完成 %d 组, 耗尽于 %d 秒 (第%d组), 最大训练功率 %.1f W", result.CompletedReps, result.ExhaustedAt, result.ExhaustedRep, result.MaxWorkPower)
+	if result.Feasible || result.CompletedReps >= session.Reps || result.CompletedReps < 1 {
+		t.Errorf("完成组数 = %d, 可以完成 = %v", result.CompletedReps, result.Feasible)
+	}
+	if result.ExhaustedRep != result.CompletedReps+1 {
+		t.Errorf("耗尽组 = %d, 完成组数 = %d", result.ExhaustedRep, result.CompletedReps)
+	}
+	if result.MaxWorkPower <= cp || result.MaxWorkPower >= session.WorkPower {
+		t.Errorf("最大训练功率 = %.1f, 应在 CP 与 %.1f 之间", result.MaxWorkPower, session.WorkPower)
+	}
+
+	// 以最大训练功率可以完成全部组数
+	session.WorkPower = result.MaxWorkPower
+	result, err = criticalpower.SimulateIntervals(cp, wprime, session, criticalpower.WbalDifferential)
+	if err != nil {
+		t.Fatalf("计算失败: %v", err)
+	}
+	if !result.Feasible || result.CompletedReps != session.Reps || math.Abs(result.MinBalance) > 1 {
+		t.Errorf("最大训练功率下: 完成 %d 组, 最小 W'bal %.2f", result.CompletedReps, result.MinBalance)
+	}
+
+	// 单组的最大功率为 CP + W'/t
+	session.Reps = 1
+	result, _ = criticalpower.SimulateIntervals(cp, wprime, session, criticalpower.WbalIntegral)
+	if want := cp + wprime/180; math.Abs(result.MaxWorkPower-want) > 1e-6 {
+		t.Errorf("单组最大功率 = %.3f, 期望 %.3f", result.MaxWorkPower, want)
+	}
+
+	// 没有给出训练功率时应返回错误
+	session.WorkPower = 0
+	if _, err := criticalpower.SimulateIntervals(cp, wprime, session, criticalpower.WbalIntegral); err == nil {
+		t.Error("训练功率为 0 时应返回错误")
+	}
+}
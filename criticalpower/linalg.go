@@ -0,0 +1,95 @@
+package criticalpower
+
+import "math"
+
+// solveLinear 使用部分主元高斯消元求解 a·x = b，a 与 b 会被修改
+func solveLinear(a [][]float64, b []float64) ([]float64, bool) {
+	n := len(b)
+	for col := range n {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-300 {
+			return nil, false
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		b[col], b[pivot] = b[pivot], b[col]
+
+		for row := col + 1; row < n; row++ {
+			factor := a[row][col] / a[col][col]
+			for k := col; k < n; k++ {
+				a[row][k] -= factor * a[col][k]
+			}
+			b[row] -= factor * b[col]
+		}
+	}
+
+	x := make([]float64, n)
+	for row := n - 1; row >= 0; row-- {
+		sum := b[row]
+		for k := row + 1; k < n; k++ {
+			sum -= a[row][k] * x[k]
+		}
+		x[row] = sum / a[row][row]
+	}
+	for _, v := range x {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, false
+		}
+	}
+	return x, true
+}
+
+// invert 求矩阵的逆，矩阵奇异时返回 false
+func invert(a [][]float64) ([][]float64, bool) {
+	n := len(a)
+	inverse := make([][]float64, n)
+	for i := range inverse {
+		inverse[i] = make([]float64, n)
+	}
+	for col := range n {
+		m := make([][]float64, n)
+		for i := range m {
+			m[i] = append([]float64(nil), a[i]...)
+		}
+		e := make([]float64, n)
+		e[col] = 1
+		x, ok := solveLinear(m, e)
+		if !ok {
+			return nil, false
+		}
+		for row := range n {
+			inverse[row][col] = x[row]
+		}
+	}
+	return inverse, true
+}
+
+// cholesky 对对称正定矩阵做 Cholesky 分解 a = L·Lᵀ，返回下三角矩阵 L，矩阵不正定时返回 false
+func cholesky(a [][]float64) ([][]float64, bool) {
+	n := len(a)
+	l := make([][]float64, n)
+	for i := range l {
+		l[i] = make([]float64, n)
+	}
+	for i := range n {
+		for j := 0; j <= i; j++ {
+			sum := a[i][j]
+			for k := range j {
+				sum -= l[i][k] * l[j][k]
+			}
+			if i == j {
+				if sum <= 0 {
+					return nil, false
+				}
+				l[i][i] = math.Sqrt(sum)
+			} else {
+				l[i][j] = sum / l[j][j]
+			}
+		}
+	}
+	return l, true
+}
@@ -0,0 +1,69 @@
+package criticalpower
+
+import (
+	"errors"
+	"math"
+)
+
+// NormalizedPowerWindow 计算标准化功率时滚动平均的窗口（秒）
+const NormalizedPowerWindow = 30
+
+// RideLoad 单次骑行的训练负荷
+type RideLoad struct {
+	Duration         int     // 时长（秒）
+	Work             float64 // 做功（千焦）
+	AveragePower     float64 // 平均功率
+	NormalizedPower  float64 // 标准化功率 NP
+	IntensityFactor  float64 // 强度因子 IF = NP / CP
+	TSS              float64 // 训练压力分数 TSS = t × NP × IF / (CP × 3600) × 100
+	VariabilityIndex float64 // 变异指数 VI = NP / 平均功率，平均功率为 0 时为 0
+}
+
+// NormalizedPower 计算 1 Hz 功率流的标准化功率：30 秒滚动平均的 4 次方均值再开 4 次方
+// 功率流短于 30 秒时返回平均功率
+func NormalizedPower(power []float64) float64 {
+	if len(power) == 0 {
+		return 0
+	}
+	prefix := prefixSum(power)
+	if len(power) < NormalizedPowerWindow {
+		return prefix[len(power)] / float64(len(power))
+	}
+
+	var sum float64
+	for end := NormalizedPowerWindow; end < len(prefix); end++ {
+		rolling := (prefix[end] - prefix[end-NormalizedPowerWindow]) / NormalizedPowerWindow
+		sum += math.Pow(rolling, 4)
+	}
+	return math.Pow(sum/float64(len(prefix)-NormalizedPowerWindow), 0.25)
+}
+
+// TrainingLoad 计算 1 Hz 功率流相对于 CP 的训练负荷，负功率按 0 计算
+func TrainingLoad(power []float64, cp float64) (*RideLoad, error) {
+	if cp <= 0 {
+		return nil, errors.New("CP 必须大于0")
+	}
+	if len(power) == 0 {
+		return nil, errors.New("功率流为空")
+	}
+
+	prefix := prefixSum(power)
+	duration := len(power)
+	load := &RideLoad{
+		Duration:        duration,
+		Work:            prefix[duration] / 1000,
+		AveragePower:    prefix[duration] / float64(duration),
+		NormalizedPower: NormalizedPower(power),
+	}
+	load.IntensityFactor = load.NormalizedPower / cp
+	load.TSS = float64(duration) * load.NormalizedPower * load.IntensityFactor / (cp * 3600) * 100
+	if load.AveragePower > 0 {
+		load.VariabilityIndex = load.NormalizedPower / load.AveragePower
+	}
+	return load, nil
+}
+
+// TrainingLoad 使用模型的 CP 计算 1 Hz 功率流的训练负荷
+func (m *CriticalPowerModel) TrainingLoad(power []float64) (*RideLoad, error) {
+	return TrainingLoad(power, m.CP)
+}
@@ -0,0 +1,54 @@
+package criticalpower_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Equationzhao/power/criticalpower"
+)
+
+// TestTrainingLoad 在 CP 骑行一小时，TSS 为 100
+func TestTrainingLoad(t *testing.T) {
+	power := make([]float64, 3600)
+	for i := range power {
+		power[i] = 250
+	}
+	load, err := criticalpower.TrainingLoad(power, 250)
+	if err != nil {
+		t.Fatalf("计算失败: %v", err)
+	}
+	if math.Abs(load.NormalizedPower-250) > 1e-9 || math.Abs(load.IntensityFactor-1) > 1e-9 ||
+		math.Abs(load.TSS-100) > 1e-9 || math.Abs(load.Work-900) > 1e-9 || math.Abs(load.VariabilityIndex-1) > 1e-9 {
+		t.Errorf("训练负荷 = %+v", load)
+	}
+
+	if _, err := criticalpower.TrainingLoad(power, 0); err == nil {
+		t.Error("CP 为 0 时应返回错误")
+	}
+}
+
+// TestNormalizedPower 间歇骑行的标准化功率高于平均功率
+func TestNormalizedPower(t *testing.T) {
+	power := make([]float64, 0, 1200)
+	for range 10 {
+		for range 60 {
+			power = append(power, 400)
+		}
+		for range 60 {
+			power = append(power, 100)
+		}
+	}
+	load, err := criticalpower.TrainingLoad(power, 250)
+	if err != nil {
+		t.Fatalf("计算失败: %v", err)
+	}
+	t.Logf("NP %.1f W, VI %.3f, TSS %.1f", load.NormalizedPower, load.VariabilityIndex, load.TSS)
+	if load.AveragePower != 250 || load.NormalizedPower <= load.AveragePower || load.NormalizedPower >= 400 {
+		t.Errorf("平均功率 = %.1f, 标准化功率 = %.1f", load.AveragePower, load.NormalizedPower)
+	}
+
+	// 短于滚动窗口时返回平均功率
+	if np := criticalpower.NormalizedPower([]float64{100, 300}); np != 200 {
+		t.Errorf("短功率流的标准化功率 = %f, 期望 200", np)
+	}
+}
@@ -0,0 +1,79 @@
+package criticalpower
+
+import (
+	"runtime"
+	"slices"
+	"sync"
+)
+
+// DefaultMMPDurations 默认提取平均最大功率的时长（秒）
+var DefaultMMPDurations = []int{
+	1, 2, 3, 5, 10, 15, 20, 30, 45, 60, 90, 120, 180, 240, 300, 420,
+	600, 900, 1200, 1800, 2700, 3600, 5400, 7200, 10800, 14400,
+}
+
+// MeanMaximalPower 计算 1 Hz 功率流每个时长的平均最大功率，返回值下标 d-1 对应时长 d 秒
+//
+// 使用前缀和，每个时长只需一次 O(n) 的滑动窗口扫描，各时长并行计算。
+func MeanMaximalPower(power []float64) []float64 {
+	n := len(power)
+	prefix := prefixSum(power)
+	mmp := make([]float64, n)
+
+	workers := min(runtime.NumCPU(), max(n, 1))
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// 交错分配时长，使各个协程的工作量接近
+			for d := w + 1; d <= n; d += workers {
+				mmp[d-1] = maxWindowMean(prefix, d)
+			}
+		}()
+	}
+	wg.Wait()
+	return mmp
+}
+
+// MeanMaximalPoints 计算 1 Hz 功率流在给定时长（秒）的平均最大功率，可以直接用于 Fit
+// durations 为空时使用 DefaultMMPDurations，超过功率流长度的时长被忽略
+func MeanMaximalPoints(power []float64, durations []int) []PowerTimePoint {
+	if len(durations) == 0 {
+		durations = DefaultMMPDurations
+	}
+	durations = slices.Clone(durations)
+	slices.Sort(durations)
+	durations = slices.Compact(durations)
+
+	prefix := prefixSum(power)
+	points := make([]PowerTimePoint, 0, len(durations))
+	for _, d := range durations {
+		if d <= 0 || d > len(power) {
+			continue
+		}
+		points = append(points, PowerTimePoint{
+			Time:  float64(d),
+			Power: maxWindowMean(prefix, d),
+		})
+	}
+	return points
+}
+
+// prefixSum 返回前缀和，prefix[i] 为前 i 个功率之和，负功率按 0 计算
+func prefixSum(power []float64) []float64 {
+	prefix := make([]float64, len(power)+1)
+	for i, p := range power {
+		prefix[i+1] = prefix[i] + max(p, 0)
+	}
+	return prefix
+}
+
+// maxWindowMean 返回长度为 d 的窗口的最大平均值
+func maxWindowMean(prefix []float64, d int) float64 {
+	best := 0.0
+	for end := d; end < len(prefix); end++ {
+		best = max(best, prefix[end]-prefix[end-d])
+	}
+	return best / float64(d)
+}
@@ -0,0 +1,42 @@
+package criticalpower_test
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/Equationzhao/power/criticalpower"
+)
+
+// TestMeanMaximalPower 测试平均最大功率与暴力计算一致
+func TestMeanMaximalPower(t *testing.T) {
+	power := make([]float64, 600)
+	for i := range power {
+		power[i] = 150 + 300*rand.Float64()
+	}
+
+	mmp := criticalpower.MeanMaximalPower(power)
+	for d := 1; d <= len(power); d++ {
+		best := 0.0
+		for start := 0; start+d <= len(power); start++ {
+			sum := 0.0
+			for _, p := range power[start : start+d] {
+				sum += p
+			}
+			best = math.Max(best, sum/float64(d))
+		}
+		if math.Abs(mmp[d-1]-best) > 1e-6 {
+			t.Fatalf("%d 秒平均最大功率 = %.3f, 期望 %.3f", d, mmp[d-1], best)
+		}
+	}
+
+	points := criticalpower.MeanMaximalPoints(power, nil)
+	for _, point := range points {
+		if point.Time > float64(len(power)) {
+			t.Errorf("时长 %.0f 超过功率流长度", point.Time)
+		}
+		if point.Power != mmp[int(point.Time)-1] {
+			t.Errorf("%.0f 秒平均最大功率 = %.3f, 期望 %.3f", point.Time, point.Power, mmp[int(point.Time)-1])
+		}
+	}
+}
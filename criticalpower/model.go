@@ -0,0 +1,302 @@
+package criticalpower
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"slices"
+)
+
+// Model 功率-时间模型
+type Model interface {
+	// Fit 根据功率-时间数据拟合模型
+	Fit(data []PowerTimePoint) error
+	// PredictPower 预测给定时间的最大功率输出
+	PredictPower(time float64) float64
+	// PredictTime 预测维持给定功率的最大时间
+	PredictTime(power float64) (float64, error)
+	// Params 返回模型的自由参数
+	Params() map[string]float64
+}
+
+var _ Model = (*CriticalPowerModel)(nil)
+
+// ModelType 模型类型
+type ModelType string
+
+const (
+	TwoParameter   ModelType = "2p"   // Monod-Scherrer 二参数模型：P(t) = CP + W'/t
+	ThreeParameter ModelType = "3p"   // Morton 三参数模型：P(t) = CP + W'/(t + τ)
+	Exponential    ModelType = "exp"  // Hopkins 指数模型：P(t) = CP + (Pmax - CP) × e^(-t/τ)
+	OmniDomain     ModelType = "ompd" // Puchowicz 全域功率-时间模型（OmPD）
+)
+
+// DefaultModelType 默认模型类型
+const DefaultModelType = ThreeParameter
+
+// 参数名
+const (
+	ParamCP     = "cp"
+	ParamWprime = "wprime"
+	ParamPmax   = "pmax"
+	ParamTau    = "tau"
+	ParamA      = "a"
+)
+
+// TCPmax OmPD 模型中 CP 可维持的最长时间（秒），超过后功率按对数衰减
+const TCPmax = 1800.0
+
+// maxPredictTime 数值求解 PredictTime 时的最长时间（秒）
+const maxPredictTime = 7 * 24 * 3600.0
+
+// ParseModelType 解析模型类型，空字符串返回默认模型
+func ParseModelType(s string) (ModelType, error) {
+	switch t := ModelType(s); t {
+	case "":
+		return DefaultModelType, nil
+	case TwoParameter, ThreeParameter, Exponential, OmniDomain:
+		return t, nil
+	default:
+		return "", errors.New("未知的模型类型: " + s)
+	}
+}
+
+// form 模型的函数形式
+//
+// 拟合时参数以向量形式在优化器中传递，apply 负责将向量写回模型字段，
+// power 和 time 只读取模型字段，因此手动构造的模型同样可以用于预测。
+type form interface {
+	// names 自由参数名，顺序与参数向量一致
+	names() []string
+	// lower 参数下界
+	lower() []float64
+	// steps 模拟退火的基础步长
+	steps() []float64
+	// initial 随机生成初始参数，cpMin 与 cpMax 为 CP 的预估范围，maxPower 为数据中的最大功率
+	initial(r *rand.Rand, cpMin, cpMax, maxPower float64) []float64
+	// values 从模型字段读取参数向量
+	values(m *CriticalPowerModel) []float64
+	// apply 将参数向量写回模型字段
+	apply(m *CriticalPowerModel, p []float64)
+	// power 计算时间 t 对应的功率
+	power(m *CriticalPowerModel, t float64) float64
+	// time 计算维持功率 p 的时间，调用方保证 p <= Pmax
+	time(m *CriticalPowerModel, p float64) float64
+}
+
+// dataValidator 由需要特定数据才能确定参数的模型实现
+type dataValidator interface {
+	validate(data []PowerTimePoint) error
+}
+
+func formOf(t ModelType) form {
+	switch t {
+	case TwoParameter:
+		return twoParameter{}
+	case Exponential:
+		return exponential{}
+	case OmniDomain:
+		return omniDomain{}
+	default:
+		return threeParameter{}
+	}
+}
+
+// twoParameter Monod-Scherrer 二参数模型
+// 该模型在 t→0 时功率趋于无穷，Pmax 取 1 秒的预测功率
+type twoParameter struct{}
+
+func (twoParameter) names() []string  { return []string{ParamCP, ParamWprime} }
+func (twoParameter) lower() []float64 { return []float64{50, 500} }
+func (twoParameter) steps() []float64 { return []float64{5, 1000} }
+
+func (twoParameter) initial(r *rand.Rand, cpMin, cpMax, _ float64) []float64 {
+	return []float64{
+		cpMin + r.Float64()*(cpMax-cpMin),
+		5000 + 30000*r.Float64(),
+	}
+}
+
+func (twoParameter) values(m *CriticalPowerModel) []float64 {
+	return []float64{m.CP, m.Wprime}
+}
+
+func (twoParameter) apply(m *CriticalPowerModel, p []float64) {
+	m.CP, m.Wprime = p[0], p[1]
+	m.Tau = 0
+	m.Pmax = m.CP + m.Wprime
+}
+
+func (twoParameter) power(m *CriticalPowerModel, t float64) float64 {
+	return m.CP + m.Wprime/t
+}
+
+func (twoParameter) time(m *CriticalPowerModel, p float64) float64 {
+	if p <= m.CP {
+		return math.Inf(1)
+	}
+	return m.Wprime / (p - m.CP)
+}
+
+// threeParameter Morton 三参数模型
+type threeParameter struct{}
+
+func (threeParameter) names() []string  { return []string{ParamCP, ParamWprime, ParamTau} }
+func (threeParameter) lower() []float64 { return []float64{50, 500, 0.5} }
+func (threeParameter) steps() []float64 { return []float64{5, 1000, 1} }
+
+func (threeParameter) initial(r *rand.Rand, cpMin, cpMax, _ float64) []float64 {
+	return []float64{
+		cpMin + r.Float64()*(cpMax-cpMin),
+		5000 + 30000*r.Float64(),
+		0.5 + 25*r.Float64(),
+	}
+}
+
+func (threeParameter) values(m *CriticalPowerModel) []float64 {
+	return []float64{m.CP, m.Wprime, m.Tau}
+}
+
+func (threeParameter) apply(m *CriticalPowerModel, p []float64) {
+	m.CP, m.Wprime, m.Tau = p[0], p[1], p[2]
+	m.Pmax = m.CP + m.Wprime/m.Tau
+}
+
+func (threeParameter) power(m *CriticalPowerModel, t float64) float64 {
+	// P(t) = (W' + CP × (t + τ))/(t + τ)
+	return (m.Wprime + m.CP*(t+m.Tau)) / (t + m.Tau)
+}
+
+func (threeParameter) time(m *CriticalPowerModel, p float64) float64 {
+	if p <= m.CP {
+		return math.Inf(1)
+	}
+	// t = W'/(P-CP) - W'/(Pmax-CP)
+	return m.Wprime/(p-m.CP) - m.Tau
+}
+
+// exponential Hopkins 指数模型
+// W' 为曲线在 CP 之上的面积：W' = (Pmax - CP) × τ
+type exponential struct{}
+
+func (exponential) names() []string  { return []string{ParamCP, ParamPmax, ParamTau} }
+func (exponential) lower() []float64 { return []float64{50, 100, 1} }
+func (exponential) steps() []float64 { return []float64{5, 20, 2} }
+
+func (exponential) initial(r *rand.Rand, cpMin, cpMax, maxPower float64) []float64 {
+	return []float64{
+		cpMin + r.Float64()*(cpMax-cpMin),
+		maxPower * (1 + 0.2*r.Float64()),
+		10 + 90*r.Float64(),
+	}
+}
+
+func (exponential) values(m *CriticalPowerModel) []float64 {
+	return []float64{m.CP, m.Pmax, m.Tau}
+}
+
+func (exponential) apply(m *CriticalPowerModel, p []float64) {
+	m.CP, m.Pmax, m.Tau = p[0], p[1], p[2]
+	m.Wprime = (m.Pmax - m.CP) * m.Tau
+}
+
+func (exponential) power(m *CriticalPowerModel, t float64) float64 {
+	return m.CP + (m.Pmax-m.CP)*math.Exp(-t/m.Tau)
+}
+
+func (exponential) time(m *CriticalPowerModel, p float64) float64 {
+	if p <= m.CP {
+		return math.Inf(1)
+	}
+	return -m.Tau * math.Log((p-m.CP)/(m.Pmax-m.CP))
+}
+
+// omniDomain Puchowicz 全域功率-时间模型
+// https://doi.org/10.1080/02640414.2020.1735609
+//
+//	P(t) = W'/t × (1 - e^(-t × (Pmax - CP)/W')) + CP                      t <= TCPmax
+//	P(t) = W'/t × (1 - e^(-t × (Pmax - CP)/W')) + CP - A × ln(t/TCPmax)   t > TCPmax
+//
+// 超过 TCPmax 后功率随时间对数衰减，因此长时间的预测功率会低于 CP。
+// Tau 取指数项的时间常数 W'/(Pmax - CP)。
+type omniDomain struct{}
+
+func (omniDomain) names() []string  { return []string{ParamCP, ParamWprime, ParamPmax, ParamA} }
+func (omniDomain) lower() []float64 { return []float64{50, 500, 100, 0} }
+func (omniDomain) steps() []float64 { return []float64{5, 1000, 20, 2} }
+
+func (omniDomain) initial(r *rand.Rand, cpMin, cpMax, maxPower float64) []float64 {
+	return []float64{
+		cpMin + r.Float64()*(cpMax-cpMin),
+		5000 + 30000*r.Float64(),
+		maxPower * (1 + 0.2*r.Float64()),
+		50 * r.Float64(),
+	}
+}
+
+func (omniDomain) values(m *CriticalPowerModel) []float64 {
+	return []float64{m.CP, m.Wprime, m.Pmax, m.A}
+}
+
+func (omniDomain) apply(m *CriticalPowerModel, p []float64) {
+	m.CP, m.Wprime, m.Pmax, m.A = p[0], p[1], p[2], p[3]
+	m.Tau = m.Wprime / (m.Pmax - m.CP)
+}
+
+func (omniDomain) power(m *CriticalPowerModel, t float64) float64 {
+	p := m.Wprime/t*(1-math.Exp(-t*(m.Pmax-m.CP)/m.Wprime)) + m.CP
+	if t > TCPmax {
+		p -= m.A * math.Log(t/TCPmax)
+	}
+	return p
+}
+
+func (omniDomain) time(m *CriticalPowerModel, p float64) float64 {
+	if p <= m.CP && m.A <= 0 {
+		return math.Inf(1)
+	}
+	return invertPower(m, p)
+}
+
+func (omniDomain) validate(data []PowerTimePoint) error {
+	for _, point := range data {
+		if point.Time > TCPmax {
+			return nil
+		}
+	}
+	return errors.New("OmPD 模型需要至少一个超过30分钟的数据点")
+}
+
+// scaleParams 返回功率类参数都乘以 factor 后的自由参数
+//
+// 各模型的预测功率都与 τ 以外的自由参数（CP、W'、Pmax、A）成正比，τ 与 TCPmax 只影响曲线的形状，
+// 因此这些参数同时乘以 factor 得到的曲线恰好是原曲线的 factor 倍。
+func scaleParams(f form, params []float64, factor float64) []float64 {
+	scaled := slices.Clone(params)
+	for i, name := range f.names() {
+		if name != ParamTau {
+			scaled[i] *= factor
+		}
+	}
+	return scaled
+}
+
+// invertPower 使用二分法求解 PredictPower(t) = p，要求功率随时间单调递减
+func invertPower(m *CriticalPowerModel, p float64) float64 {
+	lo, hi := 0.0, 1.0
+	for m.PredictPower(hi) > p {
+		lo, hi = hi, hi*2
+		if hi > maxPredictTime {
+			return math.Inf(1)
+		}
+	}
+	for range 100 {
+		mid := (lo + hi) / 2
+		if m.PredictPower(mid) > p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
@@ -0,0 +1,124 @@
+package criticalpower_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Equationzhao/power/criticalpower"
+)
+
+// TestModelTypes 测试不同模型类型能够从理想数据中还原参数
+func TestModelTypes(t *testing.T) {
+	times := []float64{5, 15, 30, 60, 180, 300, 600, 1200, 3600, 7200}
+	tests := []struct {
+		modelType criticalpower.ModelType
+		power     func(t float64) float64
+		cp        float64
+	}{
+		{criticalpower.TwoParameter, func(t float64) float64 { return 250 + 18000/t }, 250},
+		{criticalpower.ThreeParameter, func(t float64) float64 { return 250 + 18000/(t+20) }, 250},
+		{criticalpower.Exponential, func(t float64) float64 { return 250 + 700*math.Exp(-t/40) }, 250},
+		{criticalpower.OmniDomain, func(t float64) float64 {
+			p := 18000/t*(1-math.Exp(-t*(1000-250)/18000)) + 250
+			if t > criticalpower.TCPmax {
+				p -= 30 * math.Log(t/criticalpower.TCPmax)
+			}
+			return p
+		}, 250},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.modelType), func(t *testing.T) {
+			data := make([]criticalpower.PowerTimePoint, len(times))
+			for i, ti := range times {
+				data[i] = criticalpower.PowerTimePoint{Time: ti, Power: tt.power(ti)}
+			}
+
+			// 使用确定性的 Levenberg–Marquardt，避免模拟退火的随机性使测试偶尔失败
+			model := criticalpower.New(criticalpower.WithFitter(criticalpower.LevenbergMarquardt), criticalpower.WithModel(tt.modelType))
+			if err := model.Fit(data); err != nil {
+				t.Fatalf("模型拟合失败: %v", err)
+			}
+			if model.Type() != tt.modelType {
+				t.Errorf("模型类型 = %s, 期望 %s", model.Type(), tt.modelType)
+			}
+			if math.Abs(model.CP-tt.cp)/tt.cp > 0.03 {
+				t.Errorf("CP = %.1f, 期望约 %.1f", model.CP, tt.cp)
+			}
+
+			params := model.Params()
+			if params[criticalpower.ParamCP] != model.CP {
+				t.Errorf("Params 中的 CP = %.1f, 期望 %.1f", params[criticalpower.ParamCP], model.CP)
+			}
+
+			// PredictTime 应为 PredictPower 的反函数
+			p := model.PredictPower(240)
+			d, err := model.PredictTime(p)
+			if err != nil {
+				t.Fatalf("预测时间失败: %v", err)
+			}
+			if math.Abs(d-240) > 1e-6 {
+				t.Errorf("PredictTime(PredictPower(240)) = %.3f", d)
+			}
+		})
+	}
+}
+
+func TestParseModelType(t *testing.T) {
+	if m, err := criticalpower.ParseModelType(""); err != nil || m != criticalpower.DefaultModelType {
+		t.Errorf("空字符串应返回默认模型，得到 %q, %v", m, err)
+	}
+	if _, err := criticalpower.ParseModelType("unknown"); err == nil {
+		t.Error("未知模型类型应返回错误")
+	}
+}
+
+// TestOmniDomainLongDuration 测试 OmPD 模型在长时间下的功率低于 CP
+func TestOmniDomainLongDuration(t *testing.T) {
+	model := criticalpower.New(criticalpower.WithModel(criticalpower.OmniDomain))
+	data := []criticalpower.PowerTimePoint{{Time: 60, Power: 400}, {Time: 300, Power: 300}, {Time: 1200, Power: 260}}
+	if err := model.Fit(data); err == nil {
+		t.Error("缺少超过30分钟的数据点时应返回错误")
+	}
+
+	model = criticalpower.New(criticalpower.WithModel(criticalpower.OmniDomain))
+	model.CP, model.Wprime, model.Pmax, model.A = 250, 18000, 1000, 30
+	if p := model.PredictPower(7200); p >= model.CP {
+		t.Errorf("PredictPower(7200) = %.1f, 应低于 CP", p)
+	}
+	d, err := model.PredictTime(model.CP - 10)
+	if err != nil || math.IsInf(d, 1) || d <= criticalpower.TCPmax {
+		t.Errorf("PredictTime(CP-10) = %.1f, %v", d, err)
+	}
+}
+
+// TestSetParamsValidation 测试 SetParams 拒绝非有限或超出下界的参数
+func TestSetParamsValidation(t *testing.T) {
+	valid := map[string]float64{criticalpower.ParamCP: 250, criticalpower.ParamWprime: 20000, criticalpower.ParamTau: 10}
+	invalid := []map[string]float64{
+		{criticalpower.ParamCP: 250, criticalpower.ParamWprime: 20000},
+		{criticalpower.ParamCP: 0, criticalpower.ParamWprime: 20000, criticalpower.ParamTau: 10},
+		{criticalpower.ParamCP: 250, criticalpower.ParamWprime: -1, criticalpower.ParamTau: 10},
+		{criticalpower.ParamCP: 250, criticalpower.ParamWprime: 20000, criticalpower.ParamTau: 0},
+		{criticalpower.ParamCP: math.NaN(), criticalpower.ParamWprime: 20000, criticalpower.ParamTau: 10},
+		{criticalpower.ParamCP: 250, criticalpower.ParamWprime: math.Inf(1), criticalpower.ParamTau: 10},
+	}
+
+	model := criticalpower.New()
+	if err := model.SetParams(valid); err != nil {
+		t.Fatalf("设置参数失败: %v", err)
+	}
+	for _, params := range invalid {
+		if err := model.SetParams(params); err == nil {
+			t.Errorf("参数 %v 应返回错误", params)
+		}
+	}
+	if model.CP != 250 || model.Tau != 10 || math.IsInf(model.Pmax, 0) {
+		t.Errorf("无效参数不应修改模型: %v, Pmax %.1f", model.Params(), model.Pmax)
+	}
+
+	exp := criticalpower.New(criticalpower.WithModel(criticalpower.Exponential))
+	if err := exp.SetParams(map[string]float64{criticalpower.ParamCP: 300, criticalpower.ParamPmax: 200, criticalpower.ParamTau: 30}); err == nil {
+		t.Error("Pmax 低于 CP 时应返回错误")
+	}
+}
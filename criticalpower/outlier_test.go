@@ -152,3 +152,30 @@ func generateTestDataWithOutliers() (normalData, outlierData []criticalpower.Pow
 
 	return normalData, outlierData
 }
+
+// TestPowerTimeConsistencyCascade 测试功率高于多个更短时间的点时，逐个排除这些点，删失点不参与比较
+func TestPowerTimeConsistencyCascade(t *testing.T) {
+	data := []criticalpower.PowerTimePoint{
+		{Time: 60, Power: 400},
+		{Time: 120, Power: 350},
+		{Time: 240, Power: 300},
+		{Time: 300, Power: 200, Censored: true},
+		{Time: 480, Power: 380},
+		{Time: 960, Power: 250},
+		{Time: 1920, Power: 220},
+	}
+	model := criticalpower.New(criticalpower.WithFitter(criticalpower.LevenbergMarquardt), criticalpower.WithOutlierDetect())
+	if err := model.Fit(data); err != nil {
+		t.Fatalf("模型拟合失败: %v", err)
+	}
+	// 480 秒的点高于 240 秒与 120 秒的点，三者都被排除
+	want := map[int]struct{}{1: {}, 2: {}, 4: {}}
+	if len(model.Outliers) != len(want) {
+		t.Fatalf("异常值 = %v, 期望 %v", model.Outliers, want)
+	}
+	for i := range want {
+		if _, ok := model.Outliers[i]; !ok {
+			t.Errorf("异常值 = %v, 期望 %v", model.Outliers, want)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package criticalpower
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// PacingSegment 计划中的一个赛段，Duration 与 Distance 只能给出一个
+type PacingSegment struct {
+	Duration float64 // 时长（秒）
+	Distance float64 // 距离（米），需要 SpeedReference 换算为时长
+	Effort   float64 // 相对强度，各赛段的功率与之成正比，0 视为 1
+}
+
+// SpeedReference 按距离计划时的速度模型
+//
+// 平路上空气阻力占主导，速度近似与功率的立方根成正比：v = Speed × (P / Power)^(1/3)
+type SpeedReference struct {
+	Power float64 // 参考功率（瓦）
+	Speed float64 // 参考功率下的速度（米/秒）
+}
+
+// speed 返回给定功率下的速度
+func (r *SpeedReference) speed(power float64) float64 {
+	return r.Speed * math.Cbrt(max(power, 0)/r.Power)
+}
+
+// PlannedSegment 赛段的配速计划
+type PlannedSegment struct {
+	Start    float64 // 开始时间（秒）
+	Duration float64 // 时长（秒）
+	Distance float64 // 距离（米），没有速度模型时为 0
+	Power    float64 // 目标功率（瓦）
+}
+
+// PacingPlan 配速计划
+type PacingPlan struct {
+	Segments     []PlannedSegment
+	Duration     float64   // 总时长（秒）
+	Distance     float64   // 总距离（米），没有速度模型时为 0
+	AveragePower float64   // 按时间加权的平均功率
+	Balance      []float64 // 按 W'bal 微分模型预测的每秒 W'bal（焦耳）
+	MinBalance   float64   // 最小 W'bal
+	MinTime      float64   // 最小 W'bal 出现的时间（秒）
+}
+
+// pacingIterations 二分查找功率系数的次数
+const pacingIterations = 60
+
+// PlanPacing 计算恰好在终点耗尽 W' 的配速计划
+//
+// 每个赛段的功率为 k × Effort，使用 W'bal 微分模型模拟整个计划，二分查找使 W'bal
+// 最小值恰好为 0 的最大系数 k。只有一个赛段时即为恒定功率 CP + W'/t，
+// 按距离计划时功率越高用时越短，同样存在唯一的解。
+// 如果前面的赛段强度高于后面的赛段，W' 可能在终点之前耗尽，之后在低于 CP 的赛段中部分恢复。
+func PlanPacing(cp, wprime float64, segments []PacingSegment, speed *SpeedReference) (*PacingPlan, error) {
+	if cp <= 0 || wprime <= 0 {
+		return nil, errors.New("CP 与 W' 必须大于0")
+	}
+	if len(segments) == 0 {
+		return nil, errors.New("赛段为空")
+	}
+	if speed != nil && (speed.Power <= 0 || speed.Speed <= 0) {
+		return nil, errors.New("参考功率与速度必须大于0")
+	}
+	minEffort := math.Inf(1)
+	for i, s := range segments {
+		if s.Effort < 0 {
+			return nil, fmt.Errorf("第%d个赛段的相对强度不能为负", i+1)
+		}
+		if (s.Duration > 0) == (s.Distance > 0) {
+			return nil, fmt.Errorf("第%d个赛段需要给出时长或距离之一", i+1)
+		}
+		if s.Distance > 0 && speed == nil {
+			return nil, errors.New("按距离计划时需要参考功率与速度")
+		}
+		minEffort = min(minEffort, effort(s))
+	}
+
+	p := &pacer{cp: cp, wprime: wprime, segments: segments, speed: speed}
+
+	// 先倍增找到不可行的上界，再二分
+	low, high := 0.0, cp/minEffort
+	for p.simulate(high).MinBalance >= 0 {
+		low = high
+		high *= 2
+		if math.IsInf(high, 1) {
+			return nil, errors.New("无法找到耗尽 W' 的配速")
+		}
+	}
+	for range pacingIterations {
+		mid := (low + high) / 2
+		if p.simulate(mid).MinBalance >= 0 {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	if low == 0 {
+		return nil, errors.New("无法找到耗尽 W' 的配速")
+	}
+	return p.simulate(low), nil
+}
+
+// PlanPacing 使用模型的 CP 与 W' 计算配速计划
+func (m *CriticalPowerModel) PlanPacing(segments []PacingSegment, speed *SpeedReference) (*PacingPlan, error) {
+	return PlanPacing(m.CP, m.Wprime, segments, speed)
+}
+
+func effort(s PacingSegment) float64 {
+	if s.Effort == 0 {
+		return 1
+	}
+	return s.Effort
+}
+
+type pacer struct {
+	cp, wprime float64
+	segments   []PacingSegment
+	speed      *SpeedReference
+}
+
+// simulate 按功率系数 k 模拟计划，以 1 秒为步长计算 W'bal
+func (p *pacer) simulate(k float64) *PacingPlan {
+	plan := &PacingPlan{
+		Segments:   make([]PlannedSegment, len(p.segments)),
+		MinBalance: p.wprime,
+	}
+	balance := p.wprime
+	elapsed, work := 0.0, 0.0
+	nextSample := 1.0
+	for i, s := range p.segments {
+		power := k * effort(s)
+		duration := s.Duration
+		if s.Distance > 0 {
+			duration = s.Distance / p.speed.speed(power)
+		}
+		distance := s.Distance
+		if distance == 0 && p.speed != nil {
+			distance = p.speed.speed(power) * duration
+		}
+		plan.Segments[i] = PlannedSegment{Start: elapsed, Duration: duration, Distance: distance, Power: power}
+
+		end := elapsed + duration
+		for elapsed < end {
+			step := min(nextSample, end)
+			dt := step - elapsed
+			if power > p.cp {
+				balance -= (power - p.cp) * dt
+			} else {
+				balance += (p.wprime - balance) * (p.cp - power) / p.wprime * dt
+			}
+			elapsed = step
+			if balance < plan.MinBalance {
+				plan.MinBalance = balance
+				plan.MinTime = elapsed
+			}
+			if elapsed == nextSample {
+				plan.Balance = append(plan.Balance, balance)
+				nextSample++
+			}
+		}
+		work += power * duration
+		plan.Distance += distance
+	}
+	// 最后不足 1 秒的部分
+	if elapsed > nextSample-1 {
+		plan.Balance = append(plan.Balance, balance)
+	}
+	plan.Duration = elapsed
+	if elapsed > 0 {
+		plan.AveragePower = work / elapsed
+	}
+	return plan
+}
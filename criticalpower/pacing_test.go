@@ -0,0 +1,51 @@
+package criticalpower_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Equationzhao/power/criticalpower"
+)
+
+// TestPlanPacingConstant 恒定功率计划为 CP + W'/t
+func TestPlanPacingConstant(t *testing.T) {
+	cp, wprime := 250.0, 20000.0
+	plan, err := criticalpower.PlanPacing(cp, wprime, []criticalpower.PacingSegment{{Duration: 1200}}, nil)
+	if err != nil {
+		t.Fatalf("计算失败: %v", err)
+	}
+	if want := cp + wprime/1200; math.Abs(plan.Segments[0].Power-want) > 1e-6 {
+		t.Errorf("功率 = %.3f, 期望 %.3f", plan.Segments[0].Power, want)
+	}
+	if len(plan.Balance) != 1200 || math.Abs(plan.Balance[1199]) > 1e-3 || plan.MinTime != 1200 {
+		t.Errorf("W'bal 长度 = %d, 终点 = %.3f, 最小值时间 = %.1f", len(plan.Balance), plan.Balance[len(plan.Balance)-1], plan.MinTime)
+	}
+}
+
+// TestPlanPacingDistance 分段按距离计划，终点恰好耗尽 W'
+func TestPlanPacingDistance(t *testing.T) {
+	speed := &criticalpower.SpeedReference{Power: 250, Speed: 11}
+	segments := []criticalpower.PacingSegment{
+		{Distance: 8000, Effort: 1},
+		{Distance: 2000, Effort: 1.1}, // 爬坡
+		{Distance: 10000, Effort: 1},
+	}
+	plan, err := criticalpower.PlanPacing(250, 20000, segments, speed)
+	if err != nil {
+		t.Fatalf("计算失败: %v", err)
+	}
+	t.Logf("时长 %.0f 秒, 平均功率 %.1f W, 最小 W'bal %.1f J @ %.0f 秒", plan.Duration, plan.AveragePower, plan.MinBalance, plan.MinTime)
+	if math.Abs(plan.Distance-20000) > 1e-6 {
+		t.Errorf("总距离 = %.1f", plan.Distance)
+	}
+	if math.Abs(plan.MinBalance) > 1e-3 || math.Abs(plan.MinTime-plan.Duration) > 1e-6 {
+		t.Errorf("W' 应在终点耗尽: 最小值 %.3f @ %.1f, 总时长 %.1f", plan.MinBalance, plan.MinTime, plan.Duration)
+	}
+	if ratio := plan.Segments[1].Power / plan.Segments[0].Power; math.Abs(ratio-1.1) > 1e-9 {
+		t.Errorf("功率比 = %.3f, 期望 1.1", ratio)
+	}
+
+	if _, err := criticalpower.PlanPacing(250, 20000, segments, nil); err == nil {
+		t.Error("按距离计划时缺少速度模型应返回错误")
+	}
+}
@@ -3,6 +3,7 @@ package criticalpower
 
 import (
 	"errors"
+	"fmt"
 	"math"
 	"math/rand/v2"
 	"runtime"
@@ -13,23 +14,59 @@ import (
 
 // PowerTimePoint 代表功率-时间测试的一个数据点
 type PowerTimePoint struct {
-	Time  float64 // 时间（秒）
-	Power float64 // 功率（瓦特）
+	Time     float64 // 时间（秒）
+	Power    float64 // 功率（瓦特）
+	Censored bool    // 次最大努力（例如训练中的骑行），只说明真实能力不低于该功率
 }
 
-// CriticalPowerModel 表示三参数临界功率模型
+// relativeResidual 返回预测功率相对数据点的残差 (P(t) - y)/y
+// 删失点只约束曲线不低于该点，曲线高于删失点时残差为 0
+func relativeResidual(predicted float64, point PowerTimePoint) float64 {
+	r := (predicted - point.Power) / point.Power
+	if point.Censored && r > 0 {
+		return 0
+	}
+	return r
+}
+
+// maximalCount 返回最大努力数据点的个数
+func maximalCount(data []PowerTimePoint) int {
+	n := 0
+	for _, point := range data {
+		if !point.Censored {
+			n++
+		}
+	}
+	return n
+}
+
+// CriticalPowerModel 表示临界功率模型，默认使用三参数模型
 type CriticalPowerModel struct {
 	CP     float64 // 临界功率（瓦特）
 	Wprime float64 // 无氧工作容量（焦耳）
 	Pmax   float64 // 最大瞬时功率（瓦特）
 	Tau    float64 // 时间常数（秒）
+	A      float64 // 长时间衰减系数（瓦特），仅 OmPD 模型使用
 	RMSE   float64 // 拟合误差（均方根误差）
 
 	Data     []PowerTimePoint // 原始数据点
 	Outliers map[int]struct{} // 异常值索引
 
-	numRuns       int  // 运行次数
-	outlierDetect bool // 是否检测异常值
+	Covariance *Covariance // 参数协方差，数据点不足时为 nil
+	Bootstrap  *Bootstrap  // 自助法不确定性估计，仅在使用 WithBootstrap 时计算
+	Posterior  *Posterior  // 后验分布，仅在使用 Bayesian 拟合算法时计算
+	Anchors    []int       // 锚点在 Data 中的索引，仅在使用 Envelope 拟合算法时计算
+
+	modelType     ModelType // 模型类型
+	fitter        Fitter    // 拟合算法
+	numRuns       int       // 运行次数，仅用于模拟退火
+	outlierDetect bool      // 是否检测异常值
+
+	bootstrapSamples int // 自助法重抽样次数，0 表示不计算
+
+	priors      Priors  // 贝叶斯拟合中覆盖默认先验的参数
+	priorMass   float64 // 默认先验使用的体重
+	mcmcSamples int     // 贝叶斯拟合保留的后验样本数
 }
 
 const DefaultNumRuns = 10000
@@ -37,7 +74,7 @@ const DefaultNumRuns = 10000
 // ModelOption 模型选项
 type ModelOption func(*CriticalPowerModel)
 
-// WithRunTimes 设置运行次数
+// WithRunTimes 设置模拟退火的运行次数
 func WithRunTimes(numRuns int) ModelOption {
 	return func(m *CriticalPowerModel) {
 		if numRuns <= 0 {
@@ -54,9 +91,31 @@ func WithOutlierDetect() ModelOption {
 	}
 }
 
+// WithModel 设置模型类型
+func WithModel(modelType ModelType) ModelOption {
+	return func(m *CriticalPowerModel) {
+		if modelType == "" {
+			modelType = DefaultModelType
+		}
+		m.modelType = modelType
+	}
+}
+
+// WithFitter 设置拟合算法
+func WithFitter(fitter Fitter) ModelOption {
+	return func(m *CriticalPowerModel) {
+		if fitter == "" {
+			fitter = DefaultFitter
+		}
+		m.fitter = fitter
+	}
+}
+
 // New 创建模型，可以传入选项
 func New(options ...ModelOption) *CriticalPowerModel {
 	m := &CriticalPowerModel{
+		modelType:     DefaultModelType,
+		fitter:        DefaultFitter,
 		numRuns:       DefaultNumRuns,
 		outlierDetect: false,
 	}
@@ -68,27 +127,30 @@ func New(options ...ModelOption) *CriticalPowerModel {
 	return m
 }
 
-// fit 根据功率-时间数据拟合三参数临界功率模型
+// fit 根据功率-时间数据拟合临界功率模型
 func (m *CriticalPowerModel) fit() error {
-	data := m.Data
-	if len(data) < 3 {
-		return errors.New("至少需要3个数据点来拟合三参数模型")
+	f := m.form()
+	data := m.validData()
+	// 删失点只给出下界，参数需要由最大努力的数据点确定
+	if maximalCount(data) < len(f.names()) {
+		return fmt.Errorf("至少需要%d个最大努力的数据点来拟合模型", len(f.names()))
 	}
 
-	if len(m.Outliers) > 0 {
-		filteredData := make([]PowerTimePoint, 0, len(data)-len(m.Outliers))
-		for i, point := range data {
-			if _, ok := m.Outliers[i]; !ok {
-				filteredData = append(filteredData, point)
-			}
+	if v, ok := f.(dataValidator); ok {
+		if err := v.validate(data); err != nil {
+			return err
 		}
-		data = filteredData
 	}
 
-	// 获取数据中的最大功率和最小功率
+	// 获取最大努力数据中的最大功率和最小功率
 	maxPower := 0.0
 	minPower := math.MaxFloat64
+	powerList := make([]float64, 0, len(data))
 	for _, point := range data {
+		if point.Censored {
+			continue
+		}
+		powerList = append(powerList, point.Power)
 		if point.Power > maxPower {
 			maxPower = point.Power
 		}
@@ -98,24 +160,48 @@ func (m *CriticalPowerModel) fit() error {
 	}
 
 	// 预估CP范围，一般来说CP约为所有功率点的下四分位数到最小功率之间的值
-	powerList := make([]float64, len(data))
-	for i, point := range data {
-		powerList[i] = point.Power
-	}
 	slices.Sort(powerList)
 	lowerQuartileIndex := len(powerList) / 4
 	estimatedMinCP := minPower * 0.9
 	estimatedMaxCP := powerList[lowerQuartileIndex]
 
+	var bestParams []float64
+	switch m.fitter {
+	case LevenbergMarquardt:
+		bestParams = m.fitLevenbergMarquardt(data, estimatedMinCP, estimatedMaxCP, maxPower)
+	case Bayesian:
+		var err error
+		bestParams, m.Posterior, err = m.fitBayesian(data, estimatedMinCP, estimatedMaxCP, maxPower)
+		if err != nil {
+			return err
+		}
+	case Envelope:
+		var anchors []int
+		bestParams, anchors = m.fitEnvelope(data, estimatedMinCP, estimatedMaxCP, maxPower)
+		m.Anchors = m.dataIndices(anchors)
+	default:
+		bestParams = m.fitAnnealing(data, estimatedMinCP, estimatedMaxCP, maxPower)
+	}
+
+	if bestParams == nil {
+		return errors.New("模型拟合失败")
+	}
+
+	f.apply(m, bestParams)
+	m.RMSE = math.Sqrt(m.absoluteMeanSquaredError(m.fitData()))
+
+	return nil
+}
+
+// fitAnnealing 并行运行 numRuns 次随机初始值的模拟退火，返回相对误差最小的参数
+func (m *CriticalPowerModel) fitAnnealing(data []PowerTimePoint, estimatedMinCP, estimatedMaxCP, maxPower float64) []float64 {
+	f := m.form()
 	numRuns := m.numRuns
 	workers := runtime.NumCPU()
 	var wg sync.WaitGroup
 	tasks := make(chan struct{}, numRuns)
 	results := make(chan struct {
-		cp     float64
-		wprime float64
-		tau    float64
-		mse    float64
+		params []float64
 		mrse   float64
 	}, numRuns)
 
@@ -123,24 +209,21 @@ func (m *CriticalPowerModel) fit() error {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
+			trial := &CriticalPowerModel{modelType: m.modelType}
+			r := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
 			for range tasks {
-				initialCP := estimatedMinCP + rand.Float64()*(estimatedMaxCP-estimatedMinCP)
-				initialWprime := 5000 + 30000*rand.Float64()
-				initialTau := 0.5 + 25*rand.Float64()
+				initial := f.initial(r, estimatedMinCP, estimatedMaxCP, maxPower)
 
-				cp, wprime, tau, err := optimizeModel(data, initialCP, initialWprime, initialTau)
+				params, err := optimizeModel(trial, data, initial)
 				if err != nil {
 					continue
 				}
-				mrse := relativeMeanSquaredError(data, cp, wprime, tau)
-				mse := absoluteMeanSquaredError(data, cp, wprime, tau)
+				f.apply(trial, params)
+				mrse := trial.relativeMeanSquaredError(data)
 				results <- struct {
-					cp     float64
-					wprime float64
-					tau    float64
-					mse    float64
+					params []float64
 					mrse   float64
-				}{cp, wprime, tau, mse, mrse}
+				}{params, mrse}
 			}
 		}()
 	}
@@ -155,32 +238,27 @@ func (m *CriticalPowerModel) fit() error {
 		close(results)
 	}()
 
-	bestCP, bestWprime, bestTau := 0.0, 0.0, 0.0
+	var bestParams []float64
 	bestError := math.Inf(1)
-	bestErrorAbsolute := math.Inf(1)
 	for res := range results {
 		if res.mrse < bestError {
 			bestError = res.mrse
-			bestErrorAbsolute = res.mse
-			bestCP, bestWprime, bestTau = res.cp, res.wprime, res.tau
+			bestParams = res.params
 		}
 	}
-
-	if bestError == math.Inf(1) {
-		return errors.New("模型拟合失败")
-	}
-
-	m.CP = bestCP
-	m.Wprime = bestWprime
-	m.Tau = bestTau
-	m.Pmax = bestCP + bestWprime/bestTau
-	m.RMSE = math.Sqrt(bestErrorAbsolute)
-
-	return nil
+	return bestParams
 }
 
+// Fit 根据功率-时间数据拟合模型
 func (m *CriticalPowerModel) Fit(data []PowerTimePoint) error {
 	m.Data = data
+	m.Covariance = nil
+	m.Bootstrap = nil
+	m.Posterior = nil
+	m.Anchors = nil
+	if m.bootstrapSamples > 0 && (m.fitter == Bayesian || m.fitter == Envelope) {
+		return fmt.Errorf("%s 拟合算法不支持自助法置信区间", m.fitter)
+	}
 	if m.outlierDetect {
 		m.totalFilter()
 	}
@@ -188,13 +266,14 @@ func (m *CriticalPowerModel) Fit(data []PowerTimePoint) error {
 	if err != nil {
 		return err
 	}
-	if m.outlierDetect && len(m.Data) > 20 {
+	// 包络拟合中低于曲线的点是预期的，不按残差检测异常值
+	if m.outlierDetect && m.fitter != Envelope && len(m.Data) > 20 {
 		// 重新拟合模型，排除异常值
 		nonMaximalEffortCount := 0
 		for range 10 {
 			m.detectOutliers(3)
 			count := m.detectNonMaximalEffort()
-			if len(m.Data)-len(m.Outliers) < 3 {
+			if len(m.Data)-len(m.Outliers) < len(m.form().names()) {
 				break
 			}
 			if count == nonMaximalEffortCount {
@@ -207,15 +286,39 @@ func (m *CriticalPowerModel) Fit(data []PowerTimePoint) error {
 			}
 		}
 	}
+	m.Covariance = m.covariance()
+	if m.bootstrapSamples > 0 {
+		m.Bootstrap, err = m.bootstrap(m.bootstrapSamples, DefaultConfidenceLevel)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// optimizeModel 优化模型参数
-func optimizeModel(data []PowerTimePoint, initialCP, initialWprime, initialTau float64) (cp, wprime, tau float64, err error) {
+// validData 返回排除异常值后的数据
+func (m *CriticalPowerModel) validData() []PowerTimePoint {
+	if len(m.Outliers) == 0 {
+		return m.Data
+	}
+	data := make([]PowerTimePoint, 0, len(m.Data)-len(m.Outliers))
+	for i, point := range m.Data {
+		if _, ok := m.Outliers[i]; !ok {
+			data = append(data, point)
+		}
+	}
+	return data
+}
+
+// optimizeModel 优化模型参数，trial 为优化过程中用于计算误差的临时模型
+func optimizeModel(trial *CriticalPowerModel, data []PowerTimePoint, initial []float64) ([]float64, error) {
+	f := trial.form()
+	lower := f.lower()
+	steps := f.steps()
+
 	// 使用模拟退火算法优化参数
-	cp = initialCP
-	wprime = initialWprime
-	tau = initialTau
+	params := slices.Clone(initial)
+	candidate := make([]float64, len(params))
 
 	// 改进的模拟退火参数
 	temperature := 2000.0    // 更高的初始温度提供更大的搜索范围
@@ -227,42 +330,24 @@ func optimizeModel(data []PowerTimePoint, initialCP, initialWprime, initialTau f
 	noImprovementCount := 0
 	maxNoImprovements := 1000 // 如果1000次迭代没有改进，重新加热
 
-	// 自适应步长
-	cpStep := 5.0
-	wprimeStep := 1000.0
-	tauStep := 1.0
-
 	// 当前解的误差
-	currentError := relativeMeanSquaredError(data, cp, wprime, tau)
+	f.apply(trial, params)
+	currentError := trial.relativeMeanSquaredError(data)
 
 	// 最佳解
-	bestCP, bestWprime, bestTau := cp, wprime, tau
+	bestParams := slices.Clone(params)
 	bestError := currentError
 
 	for iter := 0; iter < iterations && temperature > finalTemperature; iter++ {
-		// 使用自适应步长
-		cpStepSize := cpStep * temperature / 2000.0
-		wprimeStepSize := wprimeStep * temperature / 2000.0
-		tauStepSize := tauStep * temperature / 2000.0
-
-		// 生成新的候选解
-		newCP := cp + (rand.Float64()*2-1)*cpStepSize
-		newWprime := wprime + (rand.Float64()*2-1)*wprimeStepSize
-		newTau := tau + (rand.Float64()*2-1)*tauStepSize
-
-		// 确保参数在合理范围内
-		if newCP < 50 {
-			newCP = 50
-		}
-		if newWprime < 500 {
-			newWprime = 500
-		}
-		if newTau < 0.5 {
-			newTau = 0.5
+		// 使用自适应步长生成新的候选解，并确保参数在合理范围内
+		for i := range candidate {
+			stepSize := steps[i] * temperature / 2000.0
+			candidate[i] = max(params[i]+(rand.Float64()*2-1)*stepSize, lower[i])
 		}
 
 		// 计算新解的误差
-		newError := relativeMeanSquaredError(data, newCP, newWprime, newTau)
+		f.apply(trial, candidate)
+		newError := trial.relativeMeanSquaredError(data)
 
 		// 决定是否接受新解
 		acceptNewSolution := false
@@ -281,12 +366,12 @@ func optimizeModel(data []PowerTimePoint, initialCP, initialWprime, initialTau f
 		}
 
 		if acceptNewSolution {
-			cp, wprime, tau = newCP, newWprime, newTau
+			copy(params, candidate)
 			currentError = newError
 
 			// 更新最佳解
 			if currentError < bestError {
-				bestCP, bestWprime, bestTau = cp, wprime, tau
+				copy(bestParams, params)
 				bestError = currentError
 				noImprovementCount = 0 // 重置无改进计数
 			} else {
@@ -308,24 +393,18 @@ func optimizeModel(data []PowerTimePoint, initialCP, initialWprime, initialTau f
 
 	// 如果最终误差太大，可能拟合失败
 	if bestError > 1000 {
-		return 0, 0, 0, errors.New("优化失败：误差过大")
+		return nil, errors.New("优化失败：误差过大")
 	}
 
-	return bestCP, bestWprime, bestTau, nil
+	return bestParams, nil
 }
 
 // 计算绝对均方误差(MSE)
-func absoluteMeanSquaredError(data []PowerTimePoint, cp, wprime, tau float64) float64 {
+func (m *CriticalPowerModel) absoluteMeanSquaredError(data []PowerTimePoint) float64 {
 	var sumSquaredError float64
 
 	for _, point := range data {
-		t := point.Time
-		observedPower := point.Power
-
-		// 模型预测的功率：P(t) = (W' + CP × (t + τ))/(t + τ)
-		predictedPower := (wprime + cp*(t+tau)) / (t + tau)
-
-		err := predictedPower - observedPower
+		err := relativeResidual(m.PredictPower(point.Time), point) * point.Power
 		sumSquaredError += err * err
 	}
 
@@ -333,20 +412,13 @@ func absoluteMeanSquaredError(data []PowerTimePoint, cp, wprime, tau float64) fl
 }
 
 // 计算相对均方误差(MSRE)
-func relativeMeanSquaredError(data []PowerTimePoint, cp, wprime, tau float64) float64 {
+func (m *CriticalPowerModel) relativeMeanSquaredError(data []PowerTimePoint) float64 {
 	var sumSquaredError float64
 
 	for _, point := range data {
-		t := point.Time
-		observedPower := point.Power
-
-		// 模型预测的功率：P(t) = (W' + CP × (t + τ))/(t + τ)
-		predictedPower := (wprime + cp*(t+tau)) / (t + tau)
-
-		err := predictedPower - observedPower
 		// 使用相对误差的平方，这样可以使模型更加重视低功率区域的拟合
 		// 避免高功率点支配误差计算
-		relativeErr := err / observedPower
+		relativeErr := relativeResidual(m.PredictPower(point.Time), point)
 		sumSquaredError += relativeErr * relativeErr
 	}
 
@@ -354,25 +426,85 @@ func relativeMeanSquaredError(data []PowerTimePoint, cp, wprime, tau float64) fl
 	return sumSquaredError / float64(len(data))
 }
 
+// form 返回模型的函数形式
+func (m *CriticalPowerModel) form() form {
+	return formOf(m.modelType)
+}
+
+// Type 返回模型类型
+func (m *CriticalPowerModel) Type() ModelType {
+	if m.modelType == "" {
+		return DefaultModelType
+	}
+	return m.modelType
+}
+
+// Fitter 返回拟合算法
+func (m *CriticalPowerModel) Fitter() Fitter {
+	if m.fitter == "" {
+		return DefaultFitter
+	}
+	return m.fitter
+}
+
+// SetParams 使用自由参数设置模型，其余字段由参数推导，用于直接使用已有的拟合结果
+// 参数必须是有限的数值且不低于拟合时的下界，推导出的 Pmax 必须高于 CP，否则返回错误且不修改模型
+func (m *CriticalPowerModel) SetParams(params map[string]float64) error {
+	f := m.form()
+	names := f.names()
+	lower := f.lower()
+	values := make([]float64, len(names))
+	for i, name := range names {
+		value, ok := params[name]
+		if !ok {
+			return fmt.Errorf("%s 模型缺少参数 %s", m.Type(), name)
+		}
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return fmt.Errorf("参数 %s 必须是有限的数值", name)
+		}
+		if value < lower[i] {
+			return fmt.Errorf("参数 %s 不能小于 %g", name, lower[i])
+		}
+		values[i] = value
+	}
+
+	trial := &CriticalPowerModel{modelType: m.modelType}
+	f.apply(trial, values)
+	if math.IsInf(trial.Pmax, 0) || math.IsNaN(trial.Pmax) || trial.Pmax <= trial.CP {
+		return errors.New("参数推导出的 Pmax 必须高于 CP")
+	}
+	f.apply(m, values)
+	return nil
+}
+
+// Params 返回模型的自由参数
+func (m *CriticalPowerModel) Params() map[string]float64 {
+	f := m.form()
+	names := f.names()
+	values := f.values(m)
+	params := make(map[string]float64, len(names))
+	for i, name := range names {
+		params[name] = values[i]
+	}
+	return params
+}
+
 // PredictPower 预测给定时间的最大功率输出
 func (m *CriticalPowerModel) PredictPower(time float64) float64 {
 	if time <= 0 {
 		return m.Pmax
 	}
-	return (m.Wprime + m.CP*(time+m.Tau)) / (time + m.Tau)
+	return m.form().power(m, time)
 }
 
 // PredictTime 预测维持给定功率的最大时间
+// 低于CP的功率理论上可以无限维持，OmPD 模型除外
 func (m *CriticalPowerModel) PredictTime(power float64) (float64, error) {
-	if power <= m.CP {
-		return math.Inf(1), nil // 低于CP的功率理论上可以无限维持
-	}
 	if power > m.Pmax {
 		return 0, errors.New("功率超过最大瞬时功率")
 	}
 
-	// t = W'/(P-CP) - W'/(Pmax-CP)
-	return m.Wprime/(power-m.CP) - m.Tau, nil
+	return m.form().time(m, power), nil
 }
 
 func (m *CriticalPowerModel) predict5minPower() float64 {
@@ -396,61 +528,3 @@ func (m *CriticalPowerModel) PredictCurve(from, to time.Duration) []float64 {
 	}
 	return curve
 }
-
-type zone struct {
-	Min float64
-	Max float64
-}
-
-var (
-	// 恢复区间
-	RecoveryMinRatio = 0.0
-	RecoveryMaxRatio = 0.6
-
-	// 耐力区间
-	EnduranceMinRatio = 0.6
-	EnduranceMaxRatio = 0.9
-
-	// 节奏区间
-	TempoMinRatio = 0.9
-	TempoMaxRatio = 0.95
-
-	// 阈值区间
-	ThresholdMinRatio = 0.95
-	ThresholdMaxRatio = 1.05
-
-	// VO2Max区间
-	VO2MaxMinRatio = 1.05
-	VO2MaxMaxRatio = 1.3
-
-	// 无氧区间
-	AnaerobicMinRatio = 1.3 // 基于 CP
-	AnaerobicMaxRatio = 0.8 // 基于 Pmax
-
-	// 神经肌肉区间
-	NeuromuscularMinRatio = 0.8 // 基于 Pmax
-	NeuromuscularMaxRatio = 1.0 // 基于 Pmax
-)
-
-type TrainingZones struct {
-	RecoveryZone      zone // 恢复区间：RecoveryMinRatio 到 RecoveryMaxRatio CP
-	EnduranceZone     zone // 耐力区间：EnduranceMinRatio 到 EnduranceMaxRatio CP
-	TempoZone         zone // 节奏区间：TempoMinRatio 到 TempoMaxRatio CP
-	ThresholdZone     zone // 阈值区间：ThresholdMinRatio 到 ThresholdMaxRatio CP
-	VO2MaxZone        zone // VO2Max区间：VO2MaxMinRatio 到 VO2MaxMaxRatio CP
-	AnaerobicZone     zone // 无氧区间：AnaerobicMinRatio CP 到 AnaerobicMaxRatio Pmax
-	NeuromuscularZone zone // 神经肌肉区间：NeuromuscularMinRatio Pmax 到 NeuromuscularMaxRatio Pmax
-}
-
-// GetTrainingZones 返回基于CP的训练区间
-func (m *CriticalPowerModel) GetTrainingZones() TrainingZones {
-	return TrainingZones{
-		RecoveryZone:      zone{Min: RecoveryMinRatio * m.CP, Max: RecoveryMaxRatio * m.CP},
-		EnduranceZone:     zone{Min: EnduranceMinRatio * m.CP, Max: EnduranceMaxRatio * m.CP},
-		TempoZone:         zone{Min: TempoMinRatio * m.CP, Max: TempoMaxRatio * m.CP},
-		ThresholdZone:     zone{Min: ThresholdMinRatio * m.CP, Max: ThresholdMaxRatio * m.CP},
-		VO2MaxZone:        zone{Min: VO2MaxMinRatio * m.CP, Max: VO2MaxMaxRatio * m.CP},
-		AnaerobicZone:     zone{Min: AnaerobicMinRatio * m.CP, Max: AnaerobicMaxRatio * m.Pmax},
-		NeuromuscularZone: zone{Min: NeuromuscularMinRatio * m.Pmax, Max: NeuromuscularMaxRatio * m.Pmax},
-	}
-}
@@ -35,7 +35,8 @@ func TestPowerModel(t *testing.T) {
 	t.Logf("预测5分钟的最大功率: %.1f 瓦特\n", model.PredictPower(60*5))
 
 	// 预测维持VO2Max.Min的时间
-	duration, err := model.PredictTime(model.GetTrainingZones().VO2MaxZone.Min)
+	zones := model.GetTrainingZones(criticalpower.CogganZones())
+	duration, err := model.PredictTime(zones[4].Min)
 	if err != nil {
 		t.Logf("预测失败: %v\n", err)
 	} else {
@@ -48,12 +49,7 @@ func TestPowerModel(t *testing.T) {
 
 	// 训练区间
 	t.Log("训练区间:")
-	zones := model.GetTrainingZones()
-	t.Log("恢复区间:", zones.RecoveryZone)
-	t.Log("耐力区间:", zones.EnduranceZone)
-	t.Log("节奏区间:", zones.TempoZone)
-	t.Log("阈值区间:", zones.ThresholdZone)
-	t.Log("VO2Max区间:", zones.VO2MaxZone)
-	t.Log("无氧区间:", zones.AnaerobicZone)
-	t.Log("神经肌肉区间:", zones.NeuromuscularZone)
+	for _, zone := range zones {
+		t.Logf("%s: %.0f - %.0f 瓦特\n", zone.Name, zone.Min, zone.Max)
+	}
 }
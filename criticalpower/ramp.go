@@ -0,0 +1,277 @@
+package criticalpower
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+)
+
+// MAPMethod 从递增负荷测试中计算最大有氧功率（MAP）的方法
+type MAPMethod string
+
+const (
+	MAPLastMinute MAPMethod = "last_minute" // 最后一个完成的台阶结束前 60 秒的平均功率
+	MAPBest60     MAPMethod = "best_60s"    // 测试中最好的 60 秒平均功率
+)
+
+// DefaultMAPMethod 默认的 MAP 计算方法
+const DefaultMAPMethod = MAPLastMinute
+
+// ParseMAPMethod 解析 MAP 计算方法，空字符串返回默认方法
+func ParseMAPMethod(s string) (MAPMethod, error) {
+	switch m := MAPMethod(s); m {
+	case "":
+		return DefaultMAPMethod, nil
+	case MAPLastMinute, MAPBest60:
+		return m, nil
+	default:
+		return "", errors.New("未知的 MAP 计算方法: " + s)
+	}
+}
+
+// 由 MAP 估计 CP 的默认比例
+// 最好的 60 秒包含未完成台阶中的冲刺，通常高于最后完成的一分钟，因此比例更低
+const (
+	DefaultLastMinuteCPRatio = 0.75
+	DefaultBest60CPRatio     = 0.72
+)
+
+// RampPriorDuration 先验数据点的时长（秒）
+const RampPriorDuration = 3600
+
+// RampCPPriorSD 由 MAP 估计的 CP 作为贝叶斯先验时的相对标准差，CP 与 MAP 的比例因人而异
+const RampCPPriorSD = 0.08
+
+// 先验数据点的功率变化小于 rampPriorTolerance（瓦）或更新 rampPriorPasses 次后停止
+const (
+	rampPriorPasses    = 10
+	rampPriorTolerance = 0.1
+)
+
+const (
+	mapWindow       = 60 // MAP 的时间窗口（秒）
+	minStepDuration = 10 // 台阶的最短时长（秒）
+	changeWindow    = 5  // 判断功率变化时向后观察的时长（秒）
+	stepTolerance   = 2  // 判断最后一个台阶是否完成时允许的边界误差（秒）
+)
+
+// RampStep 递增负荷测试中的一个台阶
+type RampStep struct {
+	Start     int     // 开始时间（秒）
+	Duration  int     // 时长（秒）
+	Power     float64 // 平均功率
+	Completed bool    // 是否完成
+}
+
+// RampTest 递增负荷测试分析结果
+type RampTest struct {
+	Steps         []RampStep // 递增阶段的台阶，按时间排列
+	StepDuration  int        // 台阶时长（秒），取已完成台阶的中位数
+	StepIncrement float64    // 相邻台阶的功率增量，取中位数
+	LastMinute    float64    // 最后一个完成的台阶结束前 60 秒的平均功率
+	Best60        float64    // 最好的 60 秒平均功率
+	Method        MAPMethod  // MAP 计算方法
+	MAP           float64    // 最大有氧功率
+	CPRatio       float64    // CP 与 MAP 的比例
+	CP            float64    // 由 MAP 估计的 CP
+}
+
+// RampOption 递增负荷测试分析选项
+type RampOption func(*rampConfig)
+
+type rampConfig struct {
+	method MAPMethod
+	ratios map[MAPMethod]float64
+}
+
+// WithMAPMethod 设置 MAP 的计算方法
+func WithMAPMethod(method MAPMethod) RampOption {
+	return func(c *rampConfig) {
+		if method == "" {
+			method = DefaultMAPMethod
+		}
+		c.method = method
+	}
+}
+
+// WithCPRatio 设置某种 MAP 计算方法下 CP 与 MAP 的比例
+func WithCPRatio(method MAPMethod, ratio float64) RampOption {
+	return func(c *rampConfig) {
+		if ratio > 0 {
+			c.ratios[method] = ratio
+		}
+	}
+}
+
+// AnalyzeRampTest 分析递增负荷测试的 1 Hz 功率流
+//
+// 功率流被划分为功率近似恒定的台阶，取功率逐级递增的最长一段作为测试，
+// 最后一个台阶短于台阶时长时视为未完成。
+func AnalyzeRampTest(power []float64, options ...RampOption) (*RampTest, error) {
+	config := rampConfig{
+		method: DefaultMAPMethod,
+		ratios: map[MAPMethod]float64{
+			MAPLastMinute: DefaultLastMinuteCPRatio,
+			MAPBest60:     DefaultBest60CPRatio,
+		},
+	}
+	for _, option := range options {
+		option(&config)
+	}
+	ratio, ok := config.ratios[config.method]
+	if !ok {
+		return nil, fmt.Errorf("未知的 MAP 计算方法: %s", config.method)
+	}
+	if len(power) < mapWindow {
+		return nil, errors.New("递增负荷测试的功率流不足 60 秒")
+	}
+
+	steps := rampSteps(detectSteps(power))
+	if len(steps) < 3 {
+		return nil, errors.New("没有检测到至少 3 个递增的台阶")
+	}
+
+	result := &RampTest{Steps: steps, Method: config.method, CPRatio: ratio}
+	durations := make([]int, 0, len(steps)-1)
+	for _, s := range steps[:len(steps)-1] {
+		durations = append(durations, s.Duration)
+	}
+	slices.Sort(durations)
+	result.StepDuration = durations[len(durations)/2]
+	increments := make([]float64, len(steps)-1)
+	for i := range increments {
+		increments[i] = steps[i+1].Power - steps[i].Power
+	}
+	slices.Sort(increments)
+	result.StepIncrement = increments[len(increments)/2]
+
+	// 中间的台阶都视为完成，最后一个台阶需要达到台阶时长
+	last := -1
+	for i := range result.Steps {
+		s := &result.Steps[i]
+		s.Completed = i < len(steps)-1 || s.Duration >= result.StepDuration-stepTolerance
+		if s.Completed {
+			last = i
+		}
+	}
+
+	prefix := prefixSum(power)
+	end := result.Steps[last].Start + result.Steps[last].Duration
+	if end < mapWindow {
+		return nil, errors.New("完成的台阶不足 60 秒")
+	}
+	result.LastMinute = (prefix[end] - prefix[end-mapWindow]) / mapWindow
+	result.Best60 = maxWindowMean(prefix, mapWindow)
+
+	switch config.method {
+	case MAPBest60:
+		result.MAP = result.Best60
+	default:
+		result.MAP = result.LastMinute
+	}
+	result.CP = result.MAP * ratio
+	return result, nil
+}
+
+// detectSteps 将功率流划分为功率近似恒定的段
+// 之后 changeWindow 秒的平均功率与当前段的平均功率相差超过 max(8 W, 4%) 时开始新的段
+func detectSteps(power []float64) []RampStep {
+	prefix := prefixSum(power)
+	mean := func(from, to int) float64 { return (prefix[to] - prefix[from]) / float64(to-from) }
+
+	var steps []RampStep
+	start := 0
+	for i := minStepDuration; i+changeWindow <= len(power); i++ {
+		if i-start < minStepDuration {
+			continue
+		}
+		current := mean(start, i)
+		threshold := max(8, 0.04*current)
+		if math.Abs(mean(i, i+changeWindow)-current) <= threshold {
+			continue
+		}
+		// 观察窗口只有一部分进入新的台阶时就可能超过阈值，向后找到变化最大的位置作为边界
+		boundary, best := i, 0.0
+		for b := i; b < i+changeWindow && b+changeWindow <= len(power); b++ {
+			if diff := math.Abs(mean(b, b+changeWindow) - current); diff > best {
+				boundary, best = b, diff
+			}
+		}
+		steps = append(steps, RampStep{Start: start, Duration: boundary - start, Power: mean(start, boundary)})
+		start = boundary
+		i = boundary
+	}
+	return append(steps, RampStep{Start: start, Duration: len(power) - start, Power: mean(start, len(power))})
+}
+
+// rampSteps 返回功率逐级递增的最长连续台阶，最后一个台阶之后的放松阶段不计入
+func rampSteps(steps []RampStep) []RampStep {
+	bestStart, bestLength := 0, 0
+	for i := 0; i < len(steps); {
+		j := i + 1
+		for j < len(steps) && steps[j].Power > steps[j-1].Power {
+			j++
+		}
+		if j-i > bestLength {
+			bestStart, bestLength = i, j-i
+		}
+		i = j
+	}
+	return slices.Clone(steps[bestStart : bestStart+bestLength])
+}
+
+// CPPrior 返回由测试估计的 CP 的正态先验
+func (r *RampTest) CPPrior() Prior {
+	return Prior{Mean: r.CP, SD: r.CP * RampCPPriorSD}
+}
+
+// FitWithPrior 将测试估计的 CP 作为先验信息，使用 data 拟合 model，返回并入数据的先验点，未并入时为 nil
+//
+// 贝叶斯拟合时 CP 的先验替换为 CPPrior，不添加数据点。
+// 其他拟合算法没有先验，改为添加一个 RampPriorDuration 秒的数据点，权重与实测点相同。
+// 各模型中 CP 都是渐近线，1 小时的功率并不等于 CP，因此该点的功率取估计的 CP 加上模型在该时间高出 CP 的部分，
+// 这一部分取决于拟合结果，交替更新该点与重新拟合。模拟退火每次拟合的结果都有随机波动，
+// 迭代使用确定性的 Levenberg–Marquardt，收敛后再用 model 的拟合算法拟合一次。
+// 数据中已有不短于 RampPriorDuration 的点时，实测数据已经约束了长时间功率，不添加数据点。
+func (r *RampTest) FitWithPrior(model *CriticalPowerModel, data []PowerTimePoint) (*PowerTimePoint, error) {
+	if model.Fitter() == Bayesian {
+		priors := maps.Clone(model.priors)
+		if priors == nil {
+			priors = make(Priors)
+		}
+		priors[ParamCP] = r.CPPrior()
+		model.priors = priors
+		return nil, model.Fit(data)
+	}
+	for _, point := range data {
+		if point.Time >= RampPriorDuration {
+			return nil, model.Fit(data)
+		}
+	}
+
+	trial := model
+	if model.fitter != LevenbergMarquardt {
+		trial = &CriticalPowerModel{modelType: model.modelType, fitter: LevenbergMarquardt, outlierDetect: model.outlierDetect}
+	}
+	prior := PowerTimePoint{Time: RampPriorDuration, Power: r.CP}
+	merged := append(slices.Clone(data), prior)
+	for i := range rampPriorPasses {
+		if err := trial.Fit(merged); err != nil {
+			return nil, err
+		}
+		power := r.CP + trial.PredictPower(RampPriorDuration) - trial.CP
+		if math.Abs(power-prior.Power) < rampPriorTolerance || i == rampPriorPasses-1 {
+			break
+		}
+		prior.Power = power
+		merged[len(merged)-1] = prior
+	}
+	if trial != model {
+		if err := model.Fit(merged); err != nil {
+			return nil, err
+		}
+	}
+	return &prior, nil
+}
@@ -0,0 +1,108 @@
+package criticalpower_test
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/Equationzhao/power/criticalpower"
+)
+
+// rampTest 模拟 5 分钟 100 W 热身后从 150 W 开始每分钟增加 25 W 的递增负荷测试，
+// 完成 completed 个台阶后在下一个台阶坚持 40 秒，之后放松
+func rampTest(completed int) []float64 {
+	var power []float64
+	for i := range 300 {
+		power = append(power, 100+5*math.Sin(float64(i)))
+	}
+	for step := range completed + 1 {
+		duration := 60
+		if step == completed {
+			duration = 40
+		}
+		for i := range duration {
+			power = append(power, 150+25*float64(step)+6*math.Sin(float64(i)*1.7))
+		}
+	}
+	return append(power, slices.Repeat([]float64{80}, 120)...)
+}
+
+func TestAnalyzeRampTest(t *testing.T) {
+	result, err := criticalpower.AnalyzeRampTest(rampTest(10))
+	if err != nil {
+		t.Fatalf("分析失败: %v", err)
+	}
+	// 热身视为第一个台阶
+	if len(result.Steps) != 12 || result.StepDuration != 60 || math.Abs(result.StepIncrement-25) > 1 {
+		t.Fatalf("台阶数 = %d, 时长 = %d, 增量 = %.1f", len(result.Steps), result.StepDuration, result.StepIncrement)
+	}
+	if last := result.Steps[len(result.Steps)-1]; last.Completed || math.Abs(last.Power-400) > 2 {
+		t.Errorf("最后一个台阶 = %+v", last)
+	}
+	// 最后完成的台阶为 375 W
+	if math.Abs(result.LastMinute-375) > 2 || result.MAP != result.LastMinute {
+		t.Errorf("最后一分钟 = %.1f, MAP = %.1f", result.LastMinute, result.MAP)
+	}
+	if result.Best60 < result.LastMinute {
+		t.Errorf("最好的 60 秒 %.1f 低于最后一分钟 %.1f", result.Best60, result.LastMinute)
+	}
+	if math.Abs(result.CP-0.75*result.MAP) > 1e-9 {
+		t.Errorf("CP = %.1f", result.CP)
+	}
+
+	best, err := criticalpower.AnalyzeRampTest(rampTest(10),
+		criticalpower.WithMAPMethod(criticalpower.MAPBest60),
+		criticalpower.WithCPRatio(criticalpower.MAPBest60, 0.7))
+	if err != nil {
+		t.Fatalf("分析失败: %v", err)
+	}
+	if best.MAP != best.Best60 || math.Abs(best.CP-0.7*best.Best60) > 1e-9 {
+		t.Errorf("MAP = %.1f, CP = %.1f", best.MAP, best.CP)
+	}
+}
+
+func TestRampTestFitWithPrior(t *testing.T) {
+	result, err := criticalpower.AnalyzeRampTest(rampTest(10))
+	if err != nil {
+		t.Fatalf("分析失败: %v", err)
+	}
+	data := []criticalpower.PowerTimePoint{{Time: 180, Power: 400}, {Time: 600, Power: 330}}
+	model := criticalpower.New(criticalpower.WithModel(criticalpower.TwoParameter), criticalpower.WithFitter(criticalpower.LevenbergMarquardt))
+	prior, err := result.FitWithPrior(model, data)
+	if err != nil {
+		t.Fatalf("拟合失败: %v", err)
+	}
+	if prior == nil || len(model.Data) != 3 || len(data) != 2 {
+		t.Fatalf("先验点 = %v, 数据 = %v", prior, model.Data)
+	}
+	// 先验点高出估计 CP 的部分与模型 1 小时功率高出 CP 的部分相同
+	if above := model.PredictPower(criticalpower.RampPriorDuration) - model.CP; math.Abs(prior.Power-result.CP-above) > 0.5 || above <= 0 {
+		t.Errorf("先验点 = %v, 估计 CP = %.1f, 模型 1 小时功率高出 CP %.1f", prior, result.CP, above)
+	}
+	t.Logf("CP = %.1f, 测试估计 %.1f", model.CP, result.CP)
+
+	// 模拟退火拟合时先验点使用 Levenberg–Marquardt 迭代，与上面的结果相同
+	annealing := criticalpower.New(criticalpower.WithModel(criticalpower.TwoParameter), criticalpower.WithRunTimes(2000))
+	if got, err := result.FitWithPrior(annealing, data); err != nil || got == nil || *got != *prior {
+		t.Errorf("模拟退火的先验点 = %v, 期望 %v, 错误 = %v", got, prior, err)
+	}
+
+	// 已有长时间数据时不加入先验点
+	long := append(slices.Clone(data), criticalpower.PowerTimePoint{Time: 3600, Power: 270})
+	if prior, err := result.FitWithPrior(model, long); err != nil || prior != nil || len(model.Data) != 3 {
+		t.Errorf("先验点 = %v, 数据 = %v, 错误 = %v", prior, model.Data, err)
+	}
+
+	// 贝叶斯拟合使用 CP 的先验
+	bayes := criticalpower.New(criticalpower.WithFitter(criticalpower.Bayesian), criticalpower.WithMCMCSamples(500))
+	if prior, err := result.FitWithPrior(bayes, data[:1]); err == nil || prior != nil {
+		t.Errorf("数据点不足时应返回错误, 先验点 = %v", prior)
+	}
+	three := append(slices.Clone(data), criticalpower.PowerTimePoint{Time: 60, Power: 520})
+	if _, err := result.FitWithPrior(bayes, three); err != nil {
+		t.Fatalf("拟合失败: %v", err)
+	}
+	if got := bayes.Posterior.Priors[criticalpower.ParamCP]; got != result.CPPrior() || len(bayes.Data) != 3 {
+		t.Errorf("CP 先验 = %+v, 期望 %+v", got, result.CPPrior())
+	}
+}
@@ -0,0 +1,275 @@
+package criticalpower
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Sport 运动项目
+type Sport string
+
+const (
+	Cycling  Sport = "cycling"  // 骑行，使用功率
+	Running  Sport = "running"  // 跑步，使用速度
+	Swimming Sport = "swimming" // 游泳，使用速度
+)
+
+// DefaultSport 默认运动项目
+const DefaultSport = Cycling
+
+// DefaultPaceZoneScheme 跑步与游泳默认的区间方案名，Coggan 区间是为骑行设计的
+const DefaultPaceZoneScheme = ZoneSchemeSeiler
+
+// ParseSport 解析运动项目，空字符串返回默认项目
+func ParseSport(s string) (Sport, error) {
+	switch sport := Sport(s); sport {
+	case "":
+		return DefaultSport, nil
+	case Cycling, Running, Swimming:
+		return sport, nil
+	default:
+		return "", errors.New("未知的运动项目: " + s)
+	}
+}
+
+// PaceDistance 返回配速的距离单位（米）：跑步为每公里，游泳为每 100 米
+func (s Sport) PaceDistance() float64 {
+	if s == Swimming {
+		return 100
+	}
+	return 1000
+}
+
+// RaceDistances 返回运动项目的常见比赛距离（米）
+func (s Sport) RaceDistances() []float64 {
+	switch s {
+	case Running:
+		return []float64{1500, 3000, 5000, 10000, 21097.5, 42195}
+	case Swimming:
+		return []float64{100, 200, 400, 800, 1500, 3800}
+	default:
+		return nil
+	}
+}
+
+// speedScale 拟合前将速度（m/s）放大到功率的量级
+//
+// 模型曲线随 τ 以外的参数等比例缩放（见 scaleParams），拟合使用相对误差，
+// 因此对放大后的数据拟合再把参数缩小回去与直接对速度拟合等价，
+// 并且可以沿用按功率设定的参数下界、步长与异常值检测阈值。
+const speedScale = 100.0
+
+// SpeedTimePoint 速度-时间数据点
+type SpeedTimePoint struct {
+	Time  float64 // 时间（秒）
+	Speed float64 // 平均速度（m/s）
+}
+
+// DistanceTimePoint 距离-时间数据点，例如一次全力跑完 5 km 的成绩
+type DistanceTimePoint struct {
+	Distance float64 // 距离（米）
+	Time     float64 // 用时（秒）
+}
+
+// SpeedPoints 将距离-时间数据换算为速度-时间数据
+func SpeedPoints(data []DistanceTimePoint) []SpeedTimePoint {
+	points := make([]SpeedTimePoint, len(data))
+	for i, d := range data {
+		points[i] = SpeedTimePoint{Time: d.Time, Speed: d.Distance / d.Time}
+	}
+	return points
+}
+
+// CriticalSpeedModel 临界速度模型，用于跑步与游泳
+//
+// 与临界功率模型的形式相同，以速度代替功率：二参数模型为 v(t) = CS + D'/t，
+// 即距离 d(t) = CS × t + D'。游泳的 CS 即临界游速（CSS）。
+type CriticalSpeedModel struct {
+	Sport  Sport
+	CS     float64 // 临界速度（m/s）
+	Dprime float64 // D'，高于 CS 时可以额外完成的距离（米）
+	Vmax   float64 // 最大瞬时速度（m/s）
+	Tau    float64 // 时间常数（秒）
+	A      float64 // 长时间衰减系数（m/s），仅 OmPD 模型使用
+	RMSE   float64 // 拟合误差（m/s）
+
+	Data     []SpeedTimePoint // 原始数据点
+	Outliers map[int]struct{} // 异常值索引
+	Anchors  []int            // 包络拟合的锚点索引
+
+	power *CriticalPowerModel // 以放大后的速度作为功率的模型
+}
+
+// 临界速度模型的参数名
+const (
+	ParamCS     = "cs"
+	ParamDprime = "dprime"
+	ParamVmax   = "vmax"
+)
+
+// speedParamNames 功率模型参数名到速度模型参数名的对应
+var speedParamNames = map[string]string{
+	ParamCP:     ParamCS,
+	ParamWprime: ParamDprime,
+	ParamPmax:   ParamVmax,
+	ParamTau:    ParamTau,
+	ParamA:      ParamA,
+}
+
+// NewSpeed 创建临界速度模型，options 与 New 相同
+func NewSpeed(sport Sport, options ...ModelOption) *CriticalSpeedModel {
+	return &CriticalSpeedModel{Sport: sport, power: New(options...)}
+}
+
+// Type 返回模型类型
+func (m *CriticalSpeedModel) Type() ModelType {
+	return m.power.Type()
+}
+
+// Fitter 返回拟合算法
+func (m *CriticalSpeedModel) Fitter() Fitter {
+	return m.power.Fitter()
+}
+
+// Fit 根据速度-时间数据拟合模型
+func (m *CriticalSpeedModel) Fit(data []SpeedTimePoint) error {
+	if m.Sport == Cycling {
+		return errors.New("骑行使用临界功率模型")
+	}
+	scaled := make([]PowerTimePoint, len(data))
+	for i, d := range data {
+		scaled[i] = PowerTimePoint{Time: d.Time, Power: d.Speed * speedScale}
+	}
+	m.Data = data
+	if err := m.power.Fit(scaled); err != nil {
+		return err
+	}
+	m.Outliers = m.power.Outliers
+	m.Anchors = m.power.Anchors
+	m.sync()
+	return nil
+}
+
+// FitDistance 根据距离-时间数据拟合模型
+func (m *CriticalSpeedModel) FitDistance(data []DistanceTimePoint) error {
+	return m.Fit(SpeedPoints(data))
+}
+
+// sync 从内部的功率模型读取参数
+func (m *CriticalSpeedModel) sync() {
+	p := m.power
+	m.CS, m.Dprime, m.Vmax = p.CP/speedScale, p.Wprime/speedScale, p.Pmax/speedScale
+	m.Tau, m.A, m.RMSE = p.Tau, p.A/speedScale, p.RMSE/speedScale
+}
+
+// SetParams 使用自由参数（cs、dprime、vmax、tau、a）设置模型
+func (m *CriticalSpeedModel) SetParams(params map[string]float64) error {
+	scaled := make(map[string]float64, len(params))
+	for name, speedName := range speedParamNames {
+		if value, ok := params[speedName]; ok {
+			if name != ParamTau {
+				value *= speedScale
+			}
+			scaled[name] = value
+		}
+	}
+	if err := m.power.SetParams(scaled); err != nil {
+		return err
+	}
+	m.sync()
+	return nil
+}
+
+// Params 返回模型的自由参数
+func (m *CriticalSpeedModel) Params() map[string]float64 {
+	params := make(map[string]float64)
+	for name, value := range m.power.Params() {
+		if name != ParamTau {
+			value /= speedScale
+		}
+		params[speedParamNames[name]] = value
+	}
+	return params
+}
+
+// PredictSpeed 预测给定时间能维持的最大平均速度（m/s）
+func (m *CriticalSpeedModel) PredictSpeed(time float64) float64 {
+	return m.power.PredictPower(time) / speedScale
+}
+
+// PredictTime 预测维持给定速度的最长时间
+func (m *CriticalSpeedModel) PredictTime(speed float64) (float64, error) {
+	if speed > m.Vmax {
+		return 0, errors.New("速度超过最大瞬时速度")
+	}
+	return m.power.PredictTime(speed * speedScale)
+}
+
+// PredictRaceTime 预测全力完成给定距离（米）的用时（秒）
+//
+// 全力运动 t 秒完成的距离为 v(t) × t。二参数、三参数与指数模型中距离随时间增加；
+// OmPD 模型超过 TCPmax 后速度对数衰减，距离的增速单调减小，可能在某个时间达到最大后开始减少。
+// 时间倍增直到距离不少于 distance，得到 [low, high] 区间，区间内距离单调增加，再二分查找。
+// 距离在达到 distance 之前开始减少时，模型无法完成该距离。
+func (m *CriticalSpeedModel) PredictRaceTime(distance float64) (float64, error) {
+	if distance <= 0 {
+		return 0, errors.New("距离必须大于0")
+	}
+	covered := func(t float64) float64 { return m.PredictSpeed(t) * t }
+	low, high := 0.0, 1.0
+	for covered(high) < distance {
+		if covered(2*high) < covered(high) {
+			return 0, fmt.Errorf("模型预测的距离在约 %.0f 秒后开始减少，最多完成 %.0f 米", high, covered(high))
+		}
+		low, high = high, high*2
+		if high > maxPredictTime {
+			return 0, fmt.Errorf("无法在 %.0f 秒内完成 %.0f 米", maxPredictTime, distance)
+		}
+	}
+	if covered(low) >= distance {
+		return 0, fmt.Errorf("无法确定完成 %.0f 米的用时", distance)
+	}
+	for range 100 {
+		mid := (low + high) / 2
+		if covered(mid) < distance {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return high, nil
+}
+
+// Pace 将速度（m/s）换算为配速（秒/配速距离），速度为 0 时返回 +Inf
+func (s Sport) Pace(speed float64) float64 {
+	if speed <= 0 {
+		return math.Inf(1)
+	}
+	return s.PaceDistance() / speed
+}
+
+// PaceZone 基于速度的训练区间
+type PaceZone struct {
+	Name     string
+	MinSpeed float64 // m/s
+	MaxSpeed float64 // m/s
+	SlowPace float64 // 区间下界对应的配速（秒/配速距离）
+	FastPace float64 // 区间上界对应的配速（秒/配速距离）
+}
+
+// GetPaceZones 返回模型在给定区间方案下的配速区间，CS 与 D' 分别代替 CP 与 W'
+func (m *CriticalSpeedModel) GetPaceZones(scheme ZoneScheme) []PaceZone {
+	zones := scheme.Apply(m.CS, m.Dprime, m.Vmax)
+	result := make([]PaceZone, len(zones))
+	for i, z := range zones {
+		result[i] = PaceZone{
+			Name:     z.Name,
+			MinSpeed: z.Min,
+			MaxSpeed: z.Max,
+			SlowPace: m.Sport.Pace(z.Min),
+			FastPace: m.Sport.Pace(z.Max),
+		}
+	}
+	return result
+}
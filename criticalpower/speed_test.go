@@ -0,0 +1,95 @@
+package criticalpower_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Equationzhao/power/criticalpower"
+)
+
+func TestCriticalSpeedModel(t *testing.T) {
+	// 二参数模型下距离与时间呈线性关系：d = CS × t + D'
+	const cs, dprime = 4.2, 200.0
+	var data []criticalpower.DistanceTimePoint
+	for _, d := range []float64{1500, 3000, 5000, 10000} {
+		data = append(data, criticalpower.DistanceTimePoint{Distance: d, Time: (d - dprime) / cs})
+	}
+
+	model := criticalpower.NewSpeed(criticalpower.Running,
+		criticalpower.WithModel(criticalpower.TwoParameter), criticalpower.WithFitter(criticalpower.LevenbergMarquardt))
+	if err := model.FitDistance(data); err != nil {
+		t.Fatalf("拟合失败: %v", err)
+	}
+	if math.Abs(model.CS-cs) > 1e-3 || math.Abs(model.Dprime-dprime) > 0.5 {
+		t.Errorf("CS = %.4f, D' = %.2f", model.CS, model.Dprime)
+	}
+	if params := model.Params(); params[criticalpower.ParamCS] != model.CS || params[criticalpower.ParamDprime] != model.Dprime {
+		t.Errorf("参数 = %v", params)
+	}
+
+	race, err := model.PredictRaceTime(21097.5)
+	if err != nil {
+		t.Fatalf("预测失败: %v", err)
+	}
+	if want := (21097.5 - dprime) / cs; math.Abs(race-want) > 1 {
+		t.Errorf("半程马拉松用时 = %.1f, 期望 %.1f", race, want)
+	}
+
+	zones := model.GetPaceZones(criticalpower.SeilerZones())
+	if len(zones) != 3 || math.Abs(zones[1].FastPace-1000/model.CS) > 1e-9 || !math.IsInf(zones[0].SlowPace, 1) {
+		t.Errorf("配速区间 = %+v", zones)
+	}
+}
+
+func TestCriticalSwimSpeed(t *testing.T) {
+	// 200 米与 400 米成绩计算的 CSS：(400 - 200)/(t400 - t200)
+	model := criticalpower.NewSpeed(criticalpower.Swimming, criticalpower.WithModel(criticalpower.TwoParameter))
+	if err := model.SetParams(map[string]float64{criticalpower.ParamCS: 200.0 / (330 - 155), criticalpower.ParamDprime: 200 - 155*200.0/175}); err != nil {
+		t.Fatalf("设置参数失败: %v", err)
+	}
+	for distance, want := range map[float64]float64{200: 155, 400: 330} {
+		got, err := model.PredictRaceTime(distance)
+		if err != nil || math.Abs(got-want) > 1e-6 {
+			t.Errorf("%.0f 米用时 = %.3f, 期望 %.0f (%v)", distance, got, want, err)
+		}
+	}
+	if pace := criticalpower.Swimming.Pace(model.CS); math.Abs(pace-87.5) > 1e-9 {
+		t.Errorf("CSS 配速 = %.2f 秒/100米", pace)
+	}
+
+	if err := criticalpower.NewSpeed(criticalpower.Cycling).Fit(nil); err == nil {
+		t.Error("骑行应返回错误")
+	}
+}
+
+// TestOmniDomainRaceTime 测试 OmPD 模型的比赛成绩预测：距离与预测速度一致，距离开始减少时返回错误
+func TestOmniDomainRaceTime(t *testing.T) {
+	model := criticalpower.NewSpeed(criticalpower.Running, criticalpower.WithModel(criticalpower.OmniDomain))
+	if err := model.SetParams(map[string]float64{
+		criticalpower.ParamCS: 4, criticalpower.ParamDprime: 200, criticalpower.ParamVmax: 9, criticalpower.ParamA: 0.15,
+	}); err != nil {
+		t.Fatalf("设置参数失败: %v", err)
+	}
+	for _, distance := range []float64{1500, 10000, 42195} {
+		got, err := model.PredictRaceTime(distance)
+		if err != nil {
+			t.Fatalf("%.0f 米预测失败: %v", distance, err)
+		}
+		if covered := model.PredictSpeed(got) * got; math.Abs(covered-distance) > 1e-3 {
+			t.Errorf("%.0f 米用时 %.1f 秒, 该时间的预测距离为 %.1f 米", distance, got, covered)
+		}
+	}
+
+	// 衰减很快时距离在约 1.3 小时后开始减少，最多只能完成约 10 km
+	if err := model.SetParams(map[string]float64{
+		criticalpower.ParamCS: 4, criticalpower.ParamDprime: 200, criticalpower.ParamVmax: 9, criticalpower.ParamA: 2,
+	}); err != nil {
+		t.Fatalf("设置参数失败: %v", err)
+	}
+	if got, err := model.PredictRaceTime(5000); err != nil || got > criticalpower.TCPmax {
+		t.Errorf("5000 米用时 = %.1f (%v)", got, err)
+	}
+	if got, err := model.PredictRaceTime(42195); err == nil {
+		t.Errorf("无法完成的距离应返回错误, 得到 %.1f 秒", got)
+	}
+}
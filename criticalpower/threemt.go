@@ -0,0 +1,120 @@
+package criticalpower
+
+import (
+	"errors"
+	"math"
+)
+
+// 3 分钟全力测试（3MT）
+// https://doi.org/10.1249/mss.0b013e31802dd3e6
+// 全力骑行 3 分钟后 W' 耗尽，功率降到与 CP 接近的终末功率 EP，EP 以上的做功 WEP 近似 W'
+const (
+	ThreeMinuteTestDuration = 180 // 测试时长（秒）
+	endTestWindow           = 30  // 计算 EP 的最后一段时长（秒）
+)
+
+// ThreeMinuteTestFlag 3MT 的质量问题
+type ThreeMinuteTestFlag string
+
+const (
+	// FlagPaced 峰值功率出现在 30 秒之后，说明开始阶段没有全力
+	FlagPaced ThreeMinuteTestFlag = "paced"
+	// FlagReturnedToPeak 前 30 秒之后功率又回到峰值的 90% 以上，说明有所保留
+	FlagReturnedToPeak ThreeMinuteTestFlag = "returned_to_peak"
+	// FlagEndSpurt 最后 30 秒的平均功率比之前 30 秒高 5% 以上，存在冲刺
+	FlagEndSpurt ThreeMinuteTestFlag = "end_spurt"
+	// FlagUnstableEnd 最后 30 秒功率的变异系数超过 10%，EP 尚未稳定
+	FlagUnstableEnd ThreeMinuteTestFlag = "unstable_end"
+)
+
+// ThreeMinuteTest 3MT 分析结果
+type ThreeMinuteTest struct {
+	Start       int     // 测试在功率流中的开始时间（秒）
+	EndPower    float64 // 终末功率 EP，即最后 30 秒的平均功率，作为 CP
+	WorkAboveEP float64 // EP 以上的做功 WEP（焦耳），作为 W'
+	PeakPower   float64 // 峰值功率
+	PeakTime    int     // 峰值功率出现的时间（相对测试开始，秒）
+	Flags       []ThreeMinuteTestFlag
+}
+
+// Valid 返回测试是否没有质量问题
+func (t *ThreeMinuteTest) Valid() bool {
+	return len(t.Flags) == 0
+}
+
+// Model 返回以 EP 为 CP、WEP 为 W' 的二参数模型，Pmax 取测试中实测的峰值功率
+func (t *ThreeMinuteTest) Model() *CriticalPowerModel {
+	m := New(WithModel(TwoParameter))
+	twoParameter{}.apply(m, []float64{t.EndPower, t.WorkAboveEP})
+	m.Pmax = t.PeakPower
+	return m
+}
+
+// AnalyzeThreeMinuteTest 分析 3MT 的 1 Hz 功率流
+// 功率流长于 3 分钟时，取做功最多的连续 3 分钟作为测试
+func AnalyzeThreeMinuteTest(power []float64) (*ThreeMinuteTest, error) {
+	if len(power) < ThreeMinuteTestDuration {
+		return nil, errors.New("3 分钟测试的功率流不足 180 秒")
+	}
+
+	prefix := prefixSum(power)
+	start := 0
+	for s := 1; s+ThreeMinuteTestDuration < len(prefix); s++ {
+		if prefix[s+ThreeMinuteTestDuration]-prefix[s] > prefix[start+ThreeMinuteTestDuration]-prefix[start] {
+			start = s
+		}
+	}
+	test := make([]float64, ThreeMinuteTestDuration)
+	for i := range test {
+		test[i] = max(power[start+i], 0)
+	}
+
+	result := &ThreeMinuteTest{Start: start}
+	end := test[ThreeMinuteTestDuration-endTestWindow:]
+	result.EndPower = mean(end)
+	for i, p := range test {
+		if p > result.PeakPower {
+			result.PeakPower = p
+			result.PeakTime = i
+		}
+		result.WorkAboveEP += max(p-result.EndPower, 0)
+	}
+	if result.EndPower <= 0 || result.WorkAboveEP <= 0 {
+		return nil, errors.New("测试中没有有效的功率数据")
+	}
+
+	if result.PeakTime >= endTestWindow {
+		result.Flags = append(result.Flags, FlagPaced)
+	}
+	for _, p := range test[endTestWindow:] {
+		if p >= 0.9*result.PeakPower {
+			result.Flags = append(result.Flags, FlagReturnedToPeak)
+			break
+		}
+	}
+	before := test[ThreeMinuteTestDuration-2*endTestWindow : ThreeMinuteTestDuration-endTestWindow]
+	if result.EndPower > 1.05*mean(before) {
+		result.Flags = append(result.Flags, FlagEndSpurt)
+	}
+	if stddev(end)/result.EndPower > 0.1 {
+		result.Flags = append(result.Flags, FlagUnstableEnd)
+	}
+	return result, nil
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func stddev(values []float64) float64 {
+	m := mean(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - m) * (v - m)
+	}
+	return math.Sqrt(sum / float64(len(values)))
+}
@@ -0,0 +1,72 @@
+package criticalpower_test
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/Equationzhao/power/criticalpower"
+)
+
+// threeMinuteTest 模拟全力测试：功率从峰值按指数衰减到 EP
+func threeMinuteTest(peak, ep float64) []float64 {
+	power := make([]float64, criticalpower.ThreeMinuteTestDuration)
+	for i := range power {
+		power[i] = ep + (peak-ep)*math.Exp(-float64(i)/25)
+	}
+	return power
+}
+
+func TestAnalyzeThreeMinuteTest(t *testing.T) {
+	// 前后加入热身与放松，分析应定位到测试本身
+	stream := slices.Concat(slices.Repeat([]float64{120}, 300), threeMinuteTest(900, 280), slices.Repeat([]float64{80}, 120))
+	result, err := criticalpower.AnalyzeThreeMinuteTest(stream)
+	if err != nil {
+		t.Fatalf("分析失败: %v", err)
+	}
+	if result.Start != 300 || result.PeakTime != 0 || result.PeakPower != 900 {
+		t.Errorf("开始 = %d, 峰值 = %.0f@%d", result.Start, result.PeakPower, result.PeakTime)
+	}
+	if math.Abs(result.EndPower-280) > 1 {
+		t.Errorf("EP = %.1f, 期望约 280", result.EndPower)
+	}
+	// WEP ≈ (peak - ep) × 25
+	if math.Abs(result.WorkAboveEP-620*25) > 600 {
+		t.Errorf("WEP = %.0f, 期望约 %d", result.WorkAboveEP, 620*25)
+	}
+	if !result.Valid() {
+		t.Errorf("有效的测试被标记为 %v", result.Flags)
+	}
+
+	model := result.Model()
+	params := model.Params()
+	if params[criticalpower.ParamCP] != result.EndPower || params[criticalpower.ParamWprime] != result.WorkAboveEP || model.Pmax != 900 {
+		t.Errorf("模型参数 = %v", params)
+	}
+}
+
+func TestAnalyzeThreeMinuteTestFlags(t *testing.T) {
+	// 开始阶段保留，中途加速，最后冲刺
+	paced := slices.Concat(
+		slices.Repeat([]float64{330}, 60),
+		slices.Repeat([]float64{500}, 20),
+		slices.Repeat([]float64{330}, 70),
+		slices.Repeat([]float64{380}, 30),
+	)
+	result, err := criticalpower.AnalyzeThreeMinuteTest(paced)
+	if err != nil {
+		t.Fatalf("分析失败: %v", err)
+	}
+	for _, flag := range []criticalpower.ThreeMinuteTestFlag{criticalpower.FlagPaced, criticalpower.FlagReturnedToPeak, criticalpower.FlagEndSpurt} {
+		if !slices.Contains(result.Flags, flag) {
+			t.Errorf("缺少标记 %s: %v", flag, result.Flags)
+		}
+	}
+	if result.Valid() {
+		t.Error("保留体力的测试不应有效")
+	}
+
+	if _, err := criticalpower.AnalyzeThreeMinuteTest(make([]float64, 100)); err == nil {
+		t.Error("不足 180 秒应返回错误")
+	}
+}
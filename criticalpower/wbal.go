@@ -0,0 +1,130 @@
+package criticalpower
+
+import (
+	"errors"
+	"math"
+)
+
+// WbalMethod W'bal 计算方法
+type WbalMethod string
+
+const (
+	// WbalIntegral Skiba 积分模型
+	// https://doi.org/10.1249/MSS.0b013e3182583b12
+	// W'bal(t) = W' - Σ W'exp(u) × e^(-(t-u)/τ)，τ = 546 × e^(-0.01 × D_CP) + 316，
+	// 其中 D_CP 为整段功率流中低于 CP 部分的平均差值
+	WbalIntegral WbalMethod = "integral"
+	// WbalDifferential Skiba/Froncioni 微分模型
+	// https://doi.org/10.1249/MSS.0000000000000226
+	// P > CP 时 W'bal 以 P - CP 的速率消耗，P <= CP 时以 (W' - W'bal) × (CP - P)/W' 的速率恢复
+	WbalDifferential WbalMethod = "differential"
+)
+
+// DefaultWbalMethod 默认 W'bal 计算方法
+const DefaultWbalMethod = WbalDifferential
+
+// ParseWbalMethod 解析 W'bal 计算方法，空字符串返回默认方法
+func ParseWbalMethod(s string) (WbalMethod, error) {
+	switch method := WbalMethod(s); method {
+	case "":
+		return DefaultWbalMethod, nil
+	case WbalIntegral, WbalDifferential:
+		return method, nil
+	default:
+		return "", errors.New("未知的 W'bal 计算方法: " + s)
+	}
+}
+
+// WbalResult W'bal 计算结果，时间以功率流的下标（秒）表示
+type WbalResult struct {
+	Balance     []float64 // Balance[i] 为第 i 秒结束时的 W'bal（焦耳）
+	Min         float64   // 最小 W'bal（焦耳）
+	MinTime     int       // 最小 W'bal 出现的时间
+	ExhaustedAt int       // W'bal 首次降到 0 的时间，未耗尽时为 -1
+}
+
+// Wbal 使用给定方法计算 1 Hz 功率流的 W'bal
+func Wbal(power []float64, cp, wprime float64, method WbalMethod) (*WbalResult, error) {
+	if cp <= 0 || wprime <= 0 {
+		return nil, errors.New("CP 与 W' 必须大于0")
+	}
+	if len(power) == 0 {
+		return nil, errors.New("功率流为空")
+	}
+
+	var balance []float64
+	switch method {
+	case WbalIntegral:
+		balance = wbalIntegral(power, cp, wprime)
+	case WbalDifferential, "":
+		balance = wbalDifferential(power, cp, wprime)
+	default:
+		return nil, errors.New("未知的 W'bal 计算方法: " + string(method))
+	}
+
+	result := &WbalResult{
+		Balance:     balance,
+		Min:         math.Inf(1),
+		ExhaustedAt: -1,
+	}
+	for i, b := range balance {
+		if b < result.Min {
+			result.Min = b
+			result.MinTime = i
+		}
+		if b <= 0 && result.ExhaustedAt < 0 {
+			result.ExhaustedAt = i
+		}
+	}
+	return result, nil
+}
+
+// Wbal 使用模型的 CP 与 W' 计算 1 Hz 功率流的 W'bal
+func (m *CriticalPowerModel) Wbal(power []float64, method WbalMethod) (*WbalResult, error) {
+	return Wbal(power, m.CP, m.Wprime, method)
+}
+
+// SkibaTau Skiba 积分模型的恢复时间常数（秒），dcp 为低于 CP 时的平均功率差
+func SkibaTau(dcp float64) float64 {
+	return 546*math.Exp(-0.01*dcp) + 316
+}
+
+func wbalIntegral(power []float64, cp, wprime float64) []float64 {
+	// 恢复时间常数由整段功率流中低于 CP 部分的平均差值决定
+	var recoverySum float64
+	var recoveryCount int
+	for _, p := range power {
+		if p = max(p, 0); p < cp {
+			recoverySum += cp - p
+			recoveryCount++
+		}
+	}
+	dcp := 0.0
+	if recoveryCount > 0 {
+		dcp = recoverySum / float64(recoveryCount)
+	}
+	decay := math.Exp(-1 / SkibaTau(dcp))
+
+	// 指数衰减的卷积可以递推：S(t) = S(t-1) × e^(-1/τ) + W'exp(t)
+	balance := make([]float64, len(power))
+	var expended float64
+	for i, p := range power {
+		expended = expended*decay + max(max(p, 0)-cp, 0)
+		balance[i] = wprime - expended
+	}
+	return balance
+}
+
+func wbalDifferential(power []float64, cp, wprime float64) []float64 {
+	balance := make([]float64, len(power))
+	current := wprime
+	for i, p := range power {
+		if p = max(p, 0); p > cp {
+			current -= p - cp
+		} else {
+			current += (wprime - current) * (cp - p) / wprime
+		}
+		balance[i] = current
+	}
+	return balance
+}
@@ -0,0 +1,51 @@
+package criticalpower_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Equationzhao/power/criticalpower"
+)
+
+// TestWbal 测试两种 W'bal 方法在恒定功率下的消耗与恢复
+func TestWbal(t *testing.T) {
+	cp, wprime := 250.0, 20000.0
+
+	// 350 W 持续 200 秒后 W' 恰好耗尽，随后 100 W 恢复
+	power := make([]float64, 0, 600)
+	for range 200 {
+		power = append(power, 350)
+	}
+	for range 400 {
+		power = append(power, 100)
+	}
+
+	for _, method := range []criticalpower.WbalMethod{criticalpower.WbalIntegral, criticalpower.WbalDifferential} {
+		t.Run(string(method), func(t *testing.T) {
+			result, err := criticalpower.Wbal(power, cp, wprime, method)
+			if err != nil {
+				t.Fatalf("计算失败: %v", err)
+			}
+			if len(result.Balance) != len(power) {
+				t.Fatalf("W'bal 长度 = %d, 期望 %d", len(result.Balance), len(power))
+			}
+			t.Logf("最小值 %.0f J @ %d s, 耗尽时间 %d s, 结束时 %.0f J", result.Min, result.MinTime, result.ExhaustedAt, result.Balance[len(power)-1])
+			if result.MinTime != 199 {
+				t.Errorf("最小值出现时间 = %d, 期望 199", result.MinTime)
+			}
+			if result.Balance[len(power)-1] <= result.Min || result.Balance[len(power)-1] > wprime {
+				t.Errorf("低于 CP 时 W'bal 应恢复但不超过 W'")
+			}
+		})
+	}
+
+	// 微分模型在超过 CP 时按 P - CP 线性消耗
+	result, _ := criticalpower.Wbal(power, cp, wprime, criticalpower.WbalDifferential)
+	if math.Abs(result.Min) > 1e-9 || result.ExhaustedAt != 199 {
+		t.Errorf("微分模型最小值 = %.3f, 耗尽时间 = %d", result.Min, result.ExhaustedAt)
+	}
+
+	if _, err := criticalpower.Wbal(power, 0, wprime, criticalpower.WbalIntegral); err == nil {
+		t.Error("CP 为 0 时应返回错误")
+	}
+}
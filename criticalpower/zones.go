@@ -0,0 +1,227 @@
+package criticalpower
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ZoneAnchor 区间边界的基准参数
+type ZoneAnchor string
+
+const (
+	AnchorCP   ZoneAnchor = "cp"   // 边界为 Value × CP
+	AnchorPmax ZoneAnchor = "pmax" // 边界为 Value × Pmax
+	// AnchorWprime 边界为恰好在 Value 秒内耗尽 W' 的功率，即 CP + W'/Value
+	AnchorWprime ZoneAnchor = "wprime"
+)
+
+// ZoneBound 区间的边界
+type ZoneBound struct {
+	Anchor ZoneAnchor
+	Value  float64 // 基准为 CP 或 Pmax 时为比例，基准为 W' 时为耗尽时间（秒）
+}
+
+// ZoneDefinition 区间定义
+type ZoneDefinition struct {
+	Name string
+	Min  ZoneBound
+	Max  ZoneBound
+}
+
+// ZoneScheme 训练区间划分方案，由任意数量的区间组成，区间按强度从低到高排列
+type ZoneScheme struct {
+	Name  string
+	Zones []ZoneDefinition
+}
+
+// Zone 根据模型参数计算出的训练区间（瓦）
+type Zone struct {
+	Name string
+	Min  float64
+	Max  float64
+}
+
+// 内置区间方案名
+const (
+	ZoneSchemeCoggan    = "coggan"
+	ZoneSchemeSeiler    = "seiler"
+	ZoneSchemePolarized = "polarized"
+)
+
+// DefaultZoneScheme 默认区间方案名
+const DefaultZoneScheme = ZoneSchemeCoggan
+
+// ZoneSchemeNames 内置区间方案名
+var ZoneSchemeNames = []string{ZoneSchemeCoggan, ZoneSchemeSeiler, ZoneSchemePolarized}
+
+func cpBound(ratio float64) ZoneBound {
+	return ZoneBound{Anchor: AnchorCP, Value: ratio}
+}
+
+func pmaxBound(ratio float64) ZoneBound {
+	return ZoneBound{Anchor: AnchorPmax, Value: ratio}
+}
+
+func wprimeBound(seconds float64) ZoneBound {
+	return ZoneBound{Anchor: AnchorWprime, Value: seconds}
+}
+
+// CogganZones Coggan 七区间，阈值以 CP 代替 FTP，无氧与神经肌肉区间基于 Pmax
+func CogganZones() ZoneScheme {
+	return ZoneScheme{
+		Name: ZoneSchemeCoggan,
+		Zones: []ZoneDefinition{
+			{Name: "recovery", Min: cpBound(0), Max: cpBound(0.6)},
+			{Name: "endurance", Min: cpBound(0.6), Max: cpBound(0.9)},
+			{Name: "tempo", Min: cpBound(0.9), Max: cpBound(0.95)},
+			{Name: "threshold", Min: cpBound(0.95), Max: cpBound(1.05)},
+			{Name: "vo2max", Min: cpBound(1.05), Max: cpBound(1.3)},
+			{Name: "anaerobic", Min: cpBound(1.3), Max: pmaxBound(0.8)},
+			{Name: "neuromuscular", Min: pmaxBound(0.8), Max: pmaxBound(1)},
+		},
+	}
+}
+
+// SeilerZones Seiler 三区间，以约 80% CP 作为第一通气阈值、CP 作为第二通气阈值
+// https://doi.org/10.1111/j.1600-0838.2009.01058.x
+func SeilerZones() ZoneScheme {
+	return ZoneScheme{
+		Name: ZoneSchemeSeiler,
+		Zones: []ZoneDefinition{
+			{Name: "zone1", Min: cpBound(0), Max: cpBound(0.8)},
+			{Name: "zone2", Min: cpBound(0.8), Max: cpBound(1)},
+			{Name: "zone3", Min: cpBound(1), Max: pmaxBound(1)},
+		},
+	}
+}
+
+// PolarizedZones 两极化五区间，CP 以上按 W' 的耗尽时间区分严重与极限强度域，
+// 2 分钟内耗尽 W' 的功率视为极限强度
+func PolarizedZones() ZoneScheme {
+	return ZoneScheme{
+		Name: ZoneSchemePolarized,
+		Zones: []ZoneDefinition{
+			{Name: "low", Min: cpBound(0), Max: cpBound(0.75)},
+			{Name: "moderate", Min: cpBound(0.75), Max: cpBound(0.9)},
+			{Name: "heavy", Min: cpBound(0.9), Max: cpBound(1)},
+			{Name: "severe", Min: cpBound(1), Max: wprimeBound(120)},
+			{Name: "extreme", Min: wprimeBound(120), Max: pmaxBound(1)},
+		},
+	}
+}
+
+// ParseZoneScheme 返回内置的区间方案，空字符串返回默认方案
+func ParseZoneScheme(name string) (ZoneScheme, error) {
+	switch name {
+	case "", ZoneSchemeCoggan:
+		return CogganZones(), nil
+	case ZoneSchemeSeiler:
+		return SeilerZones(), nil
+	case ZoneSchemePolarized:
+		return PolarizedZones(), nil
+	default:
+		return ZoneScheme{}, errors.New("未知的区间方案: " + name)
+	}
+}
+
+// Validate 检查区间方案是否有效
+func (s ZoneScheme) Validate() error {
+	if len(s.Zones) == 0 {
+		return errors.New("区间方案中没有区间")
+	}
+	for _, z := range s.Zones {
+		if z.Name == "" {
+			return errors.New("区间名称不能为空")
+		}
+		for _, b := range []ZoneBound{z.Min, z.Max} {
+			switch b.Anchor {
+			case AnchorCP, AnchorPmax:
+				if b.Value < 0 {
+					return fmt.Errorf("区间 %s 的边界不能为负", z.Name)
+				}
+			case AnchorWprime:
+				if b.Value <= 0 {
+					return fmt.Errorf("区间 %s 的 W' 耗尽时间必须大于0", z.Name)
+				}
+			default:
+				return fmt.Errorf("区间 %s 的边界基准未知: %s", z.Name, b.Anchor)
+			}
+		}
+	}
+	return nil
+}
+
+// Apply 根据 CP、W' 与 Pmax 计算各区间的功率范围
+// 上限低于下限时（例如 Pmax 相对 CP 很低）上限取下限
+func (s ZoneScheme) Apply(cp, wprime, pmax float64) []Zone {
+	power := func(b ZoneBound) float64 {
+		switch b.Anchor {
+		case AnchorPmax:
+			return b.Value * pmax
+		case AnchorWprime:
+			return cp + wprime/b.Value
+		default:
+			return b.Value * cp
+		}
+	}
+
+	zones := make([]Zone, len(s.Zones))
+	for i, z := range s.Zones {
+		low := power(z.Min)
+		zones[i] = Zone{Name: z.Name, Min: low, Max: max(power(z.Max), low)}
+	}
+	return zones
+}
+
+// GetTrainingZones 返回模型在给定区间方案下的训练区间
+func (m *CriticalPowerModel) GetTrainingZones(scheme ZoneScheme) []Zone {
+	return scheme.Apply(m.CP, m.Wprime, m.Pmax)
+}
+
+// ZoneTime 功率流在某个区间内的时间与做功
+type ZoneTime struct {
+	Zone
+	Seconds int     // 时间（秒）
+	Percent float64 // 占总时长的百分比
+	Work    float64 // 做功（千焦），按原始功率计算
+}
+
+// TimeInZones 统计 1 Hz 功率流在各区间内的时间与做功
+//
+// smoothing 大于 1 时先按该窗口（秒）做滚动平均，再根据平滑后的功率划分区间，
+// 以减少功率波动造成的区间跳变。功率落在区间 [Min, Max) 中，低于第一个区间的
+// 归入第一个区间，高于最后一个区间的归入最后一个区间。
+func TimeInZones(power []float64, zones []Zone, smoothing int) ([]ZoneTime, error) {
+	if len(zones) == 0 {
+		return nil, errors.New("区间为空")
+	}
+	if len(power) == 0 {
+		return nil, errors.New("功率流为空")
+	}
+
+	prefix := prefixSum(power)
+	result := make([]ZoneTime, len(zones))
+	for i, z := range zones {
+		result[i].Zone = z
+	}
+	for i := range power {
+		p := max(power[i], 0)
+		if smoothing > 1 {
+			start := max(i+1-smoothing, 0)
+			p = (prefix[i+1] - prefix[start]) / float64(i+1-start)
+		}
+		idx := len(zones) - 1
+		for j, z := range zones {
+			if p < z.Max {
+				idx = j
+				break
+			}
+		}
+		result[idx].Seconds++
+		result[idx].Work += max(power[i], 0) / 1000
+	}
+	for i := range result {
+		result[i].Percent = float64(result[i].Seconds) / float64(len(power)) * 100
+	}
+	return result, nil
+}
@@ -0,0 +1,87 @@
+package criticalpower_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Equationzhao/power/criticalpower"
+)
+
+func TestZoneSchemes(t *testing.T) {
+	cp, wprime, pmax := 250.0, 20000.0, 1000.0
+
+	for _, name := range criticalpower.ZoneSchemeNames {
+		scheme, err := criticalpower.ParseZoneScheme(name)
+		if err != nil {
+			t.Fatalf("解析 %s 失败: %v", name, err)
+		}
+		if err := scheme.Validate(); err != nil {
+			t.Errorf("%s 无效: %v", name, err)
+		}
+		zones := scheme.Apply(cp, wprime, pmax)
+		// 内置方案的区间首尾相接，覆盖 0 到 Pmax
+		if zones[0].Min != 0 || zones[len(zones)-1].Max != pmax {
+			t.Errorf("%s 区间范围 = %.0f - %.0f", name, zones[0].Min, zones[len(zones)-1].Max)
+		}
+		for i := 1; i < len(zones); i++ {
+			if zones[i].Min != zones[i-1].Max || zones[i].Max < zones[i].Min {
+				t.Errorf("%s 区间不连续: %+v, %+v", name, zones[i-1], zones[i])
+			}
+		}
+	}
+
+	// W' 基准：120 秒耗尽 W' 的功率
+	zones := criticalpower.PolarizedZones().Apply(cp, wprime, pmax)
+	if want := cp + wprime/120; math.Abs(zones[3].Max-want) > 1e-9 {
+		t.Errorf("严重强度域上限 = %.1f, 期望 %.1f", zones[3].Max, want)
+	}
+
+	if _, err := criticalpower.ParseZoneScheme("unknown"); err == nil {
+		t.Error("未知的区间方案应返回错误")
+	}
+	invalid := criticalpower.ZoneScheme{Zones: []criticalpower.ZoneDefinition{{Name: "z", Max: criticalpower.ZoneBound{Anchor: "hr"}}}}
+	if err := invalid.Validate(); err == nil {
+		t.Error("未知的边界基准应返回错误")
+	}
+}
+
+func TestTimeInZones(t *testing.T) {
+	zones := criticalpower.SeilerZones().Apply(250, 20000, 1000)
+	// 100 秒 150 W，50 秒 225 W，50 秒 400 W
+	power := make([]float64, 0, 200)
+	for _, block := range [][2]float64{{100, 150}, {50, 225}, {50, 400}} {
+		for range int(block[0]) {
+			power = append(power, block[1])
+		}
+	}
+
+	result, err := criticalpower.TimeInZones(power, zones, 0)
+	if err != nil {
+		t.Fatalf("计算失败: %v", err)
+	}
+	for i, want := range []int{100, 50, 50} {
+		if result[i].Seconds != want {
+			t.Errorf("%s 时间 = %d, 期望 %d", result[i].Name, result[i].Seconds, want)
+		}
+	}
+	if result[0].Percent != 50 || math.Abs(result[2].Work-20) > 1e-9 {
+		t.Errorf("百分比 = %.1f, 做功 = %.1f kJ", result[0].Percent, result[2].Work)
+	}
+
+	// 平滑后切换处的部分时间被归入相邻区间，但总时长与总做功不变
+	smoothed, err := criticalpower.TimeInZones(power, zones, 30)
+	if err != nil {
+		t.Fatalf("计算失败: %v", err)
+	}
+	seconds, work := 0, 0.0
+	for _, z := range smoothed {
+		seconds += z.Seconds
+		work += z.Work
+	}
+	if seconds != len(power) || math.Abs(work-(15+11.25+20)) > 1e-9 {
+		t.Errorf("平滑后总时间 = %d, 总做功 = %.2f kJ", seconds, work)
+	}
+	if smoothed[2].Seconds >= 50 {
+		t.Errorf("平滑后高强度区间时间 = %d, 应少于 50", smoothed[2].Seconds)
+	}
+}
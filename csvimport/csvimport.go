@@ -0,0 +1,307 @@
+// Package csvimport 功率-时间测试数据的 CSV/TSV 解析
+package csvimport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Equationzhao/power/criticalpower"
+)
+
+// RowError 某一行的校验错误
+type RowError struct {
+	Row     int    // 行号，从 1 开始
+	Message string // 错误信息
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("第%d行: %s", e.Row, e.Message)
+}
+
+// Result 解析结果
+type Result struct {
+	Points    []criticalpower.PowerTimePoint // 有效的数据点
+	Errors    []RowError                     // 被跳过的行及原因
+	Delimiter rune                           // 识别出的分隔符
+	HasHeader bool                           // 是否识别到表头
+}
+
+// 表头中可以识别的列名，比较时忽略大小写、空白以及括号中的单位
+var (
+	timeHeaders        = []string{"time", "duration", "seconds", "second", "secs", "sec", "s", "t", "时间", "时长"}
+	powerHeaders       = []string{"power", "watts", "watt", "w", "功率"}
+	relativeHeaders    = []string{"w/kg", "wkg", "watts/kg", "watt/kg", "relativepower", "相对功率"}
+	errNoTimeColumn    = errors.New("表头中没有时间列")
+	errNoPowerColumn   = errors.New("表头中没有功率列")
+	errWeightRequired  = errors.New("W/kg 需要提供体重")
+	errMissingDuration = errors.New("缺少时间")
+	errMissingPower    = errors.New("缺少功率")
+)
+
+type columns struct {
+	time     int
+	power    int // -1 表示不存在
+	relative int // -1 表示不存在
+}
+
+// Parse 解析 CSV 或 TSV，weight 为运动员体重（千克），仅在使用 W/kg 列时需要
+//
+// 第一行包含可识别的列名时视为表头，否则第一列为时间、第二列为功率。
+// 时间可以是秒数、"5:00"、"1:05:00" 或 "1h20m"、"90s"、"5min" 等形式；
+// 功率可以带 "W" 单位；同时存在功率与 W/kg 列时优先使用功率列。
+// 无法解析的行不会中断解析，而是记录在 Result.Errors 中。
+func Parse(r io.Reader, weight float64) (*Result, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+
+	result := &Result{Delimiter: detectDelimiter(content)}
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.Comma = result.Delimiter
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	cols := columns{time: 0, power: 1, relative: -1}
+	first := true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// 格式错误时 FieldPos 不可用，行号从 ParseError 中获取
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, err
+			}
+			result.Errors = append(result.Errors, RowError{Row: parseErr.StartLine, Message: err.Error()})
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		if isBlank(record) {
+			continue
+		}
+
+		if first {
+			first = false
+			if header, ok, err := detectHeader(record); ok {
+				if err != nil {
+					return nil, err
+				}
+				cols = header
+				result.HasHeader = true
+				continue
+			}
+		}
+
+		point, err := parseRow(record, cols, weight)
+		if err != nil {
+			result.Errors = append(result.Errors, RowError{Row: line, Message: err.Error()})
+			continue
+		}
+		result.Points = append(result.Points, point)
+	}
+	return result, nil
+}
+
+// detectDelimiter 根据第一行非空内容判断分隔符
+func detectDelimiter(content []byte) rune {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		switch {
+		case strings.Contains(line, "\t"):
+			return '\t'
+		case strings.Contains(line, ";"):
+			return ';'
+		default:
+			return ','
+		}
+	}
+	return ','
+}
+
+// detectHeader 判断是否为表头，ok 表示该行包含可识别的列名
+func detectHeader(record []string) (cols columns, ok bool, err error) {
+	cols = columns{time: -1, power: -1, relative: -1}
+	for i, cell := range record {
+		name := normalizeHeader(cell)
+		switch {
+		case cols.time < 0 && contains(timeHeaders, name):
+			cols.time = i
+		case cols.relative < 0 && contains(relativeHeaders, name):
+			cols.relative = i
+		case cols.power < 0 && contains(powerHeaders, name):
+			cols.power = i
+		}
+	}
+	if cols.time < 0 && cols.power < 0 && cols.relative < 0 {
+		return cols, false, nil
+	}
+	if cols.time < 0 {
+		return cols, true, errNoTimeColumn
+	}
+	if cols.power < 0 && cols.relative < 0 {
+		return cols, true, errNoPowerColumn
+	}
+	return cols, true, nil
+}
+
+// normalizeHeader 转为小写并去掉空白以及括号中的单位，例如 "Power (W)" -> "power"
+func normalizeHeader(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, pair := range [][2]string{{"(", ")"}, {"[", "]"}, {"（", "）"}} {
+		if i := strings.Index(s, pair[0]); i >= 0 {
+			unit := s[i+len(pair[0]):]
+			s = s[:i]
+			// 功率列的单位为 W/kg 时视为相对功率
+			if strings.HasPrefix(strings.ReplaceAll(unit, " ", ""), "w/kg") {
+				return "w/kg"
+			}
+		}
+	}
+	return strings.Join(strings.Fields(s), "")
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isBlank(record []string) bool {
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func cell(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func parseRow(record []string, cols columns, weight float64) (criticalpower.PowerTimePoint, error) {
+	var point criticalpower.PowerTimePoint
+
+	timeCell := cell(record, cols.time)
+	if timeCell == "" {
+		return point, errMissingDuration
+	}
+	seconds, err := ParseDuration(timeCell)
+	if err != nil {
+		return point, err
+	}
+
+	var power float64
+	if powerCell := cell(record, cols.power); powerCell != "" {
+		if power, err = parsePower(powerCell); err != nil {
+			return point, err
+		}
+	} else if relativeCell := cell(record, cols.relative); relativeCell != "" {
+		if weight <= 0 {
+			return point, errWeightRequired
+		}
+		relative, err := parseFinite(strings.TrimSuffix(strings.ToLower(relativeCell), "w/kg"))
+		if err != nil {
+			return point, fmt.Errorf("无效的 W/kg: %q", relativeCell)
+		}
+		power = relative * weight
+	} else {
+		return point, errMissingPower
+	}
+
+	if seconds <= 0 {
+		return point, fmt.Errorf("时间必须大于0: %q", timeCell)
+	}
+	if power <= 0 {
+		return point, fmt.Errorf("功率必须大于0: %.1f", power)
+	}
+	point.Time = seconds
+	point.Power = power
+	return point, nil
+}
+
+func parsePower(s string) (float64, error) {
+	trimmed := strings.TrimSpace(strings.TrimSuffix(strings.ToLower(s), "w"))
+	power, err := parseFinite(trimmed)
+	if err != nil {
+		return 0, fmt.Errorf("无效的功率: %q", s)
+	}
+	return power, nil
+}
+
+// parseFinite 解析有限的浮点数，NaN 与 Inf 视为无效
+func parseFinite(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("非有限的数值: %q", s)
+	}
+	return v, nil
+}
+
+// ParseDuration 解析时长并返回秒数
+// 支持 "300"、"5:00"、"1:05:00"、"1h20m"、"90s"、"5min"、"1 h 20 min" 等形式
+func ParseDuration(s string) (float64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return 0, errMissingDuration
+	}
+	if seconds, err := parseFinite(s); err == nil {
+		return seconds, nil
+	}
+
+	if strings.Contains(s, ":") {
+		parts := strings.Split(s, ":")
+		if len(parts) > 3 {
+			return 0, fmt.Errorf("无效的时间: %q", s)
+		}
+		seconds := 0.0
+		for _, part := range parts {
+			v, err := parseFinite(part)
+			if err != nil || v < 0 {
+				return 0, fmt.Errorf("无效的时间: %q", s)
+			}
+			seconds = seconds*60 + v
+		}
+		return seconds, nil
+	}
+
+	normalized := strings.Join(strings.Fields(s), "")
+	for _, r := range []struct{ from, to string }{
+		{"hours", "h"}, {"hour", "h"}, {"hrs", "h"}, {"hr", "h"},
+		{"minutes", "m"}, {"minute", "m"}, {"mins", "m"}, {"min", "m"},
+		{"seconds", "s"}, {"second", "s"}, {"secs", "s"}, {"sec", "s"},
+	} {
+		normalized = strings.ReplaceAll(normalized, r.from, r.to)
+	}
+	d, err := time.ParseDuration(normalized)
+	if err != nil {
+		return 0, fmt.Errorf("无效的时间: %q", s)
+	}
+	return d.Seconds(), nil
+}
@@ -0,0 +1,134 @@
+package csvimport_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/Equationzhao/power/csvimport"
+)
+
+func TestParseDuration(t *testing.T) {
+	cases := map[string]float64{
+		"300":        300,
+		"12.5":       12.5,
+		"5:00":       300,
+		"1:05:00":    3900,
+		"1h20m":      4800,
+		"90s":        90,
+		"5min":       300,
+		"1 h 20 min": 4800,
+	}
+	for input, want := range cases {
+		got, err := csvimport.ParseDuration(input)
+		if err != nil {
+			t.Errorf("ParseDuration(%q) 返回错误: %v", input, err)
+			continue
+		}
+		if math.Abs(got-want) > 1e-9 {
+			t.Errorf("ParseDuration(%q) = %f, 期望 %f", input, got, want)
+		}
+	}
+
+	for _, input := range []string{"abc", "1:2:3:4", "5:-1", "NaN", "+Inf", "1:infinity"} {
+		if _, err := csvimport.ParseDuration(input); err == nil {
+			t.Errorf("ParseDuration(%q) 应返回错误", input)
+		}
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	input := "Power (W)\tDuration\n400W\t5:00\nabc\t1:00\n300\t20m\n"
+	result, err := csvimport.Parse(strings.NewReader(input), 0)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if result.Delimiter != '\t' || !result.HasHeader {
+		t.Errorf("分隔符 = %q, 表头 = %v", result.Delimiter, result.HasHeader)
+	}
+	if len(result.Points) != 2 {
+		t.Fatalf("数据点数量 = %d, 期望 2", len(result.Points))
+	}
+	if p := result.Points[1]; p.Time != 1200 || p.Power != 300 {
+		t.Errorf("数据点 = %+v", p)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Row != 3 {
+		t.Errorf("错误 = %+v, 期望第3行出错", result.Errors)
+	}
+}
+
+func TestParseWithoutHeader(t *testing.T) {
+	result, err := csvimport.Parse(strings.NewReader("60,500\n\n300,0\n1200,300\n"), 0)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if result.HasHeader || len(result.Points) != 2 {
+		t.Errorf("表头 = %v, 数据点 = %+v", result.HasHeader, result.Points)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Row != 3 {
+		t.Errorf("错误 = %+v, 期望第3行出错", result.Errors)
+	}
+}
+
+func TestParseRelativePower(t *testing.T) {
+	input := "time,w/kg\n300,5\n1200,4\n"
+	result, err := csvimport.Parse(strings.NewReader(input), 70)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if len(result.Points) != 2 || result.Points[0].Power != 350 || result.Points[1].Power != 280 {
+		t.Errorf("数据点 = %+v", result.Points)
+	}
+
+	// 没有体重时每一行都应报错
+	result, err = csvimport.Parse(strings.NewReader(input), 0)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if len(result.Points) != 0 || len(result.Errors) != 2 {
+		t.Errorf("数据点 = %+v, 错误 = %+v", result.Points, result.Errors)
+	}
+}
+
+func TestParseMissingColumn(t *testing.T) {
+	if _, err := csvimport.Parse(strings.NewReader("time,heart rate\n60,150\n"), 0); err == nil {
+		t.Error("缺少功率列时应返回错误")
+	}
+}
+
+func TestParseNonFinite(t *testing.T) {
+	input := "time,power\n60,500\nNaN,400\n300,inf\n600,-Infinity\n1200,300\n1800,nan\n"
+	result, err := csvimport.Parse(strings.NewReader(input), 0)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if len(result.Points) != 2 {
+		t.Errorf("数据点 = %+v, 期望 2 个", result.Points)
+	}
+	if len(result.Errors) != 4 {
+		t.Errorf("错误 = %+v, 期望 4 行出错", result.Errors)
+	}
+}
+
+func TestParseMalformedQuote(t *testing.T) {
+	tests := []struct {
+		input  string
+		points int
+		row    int
+	}{
+		{"a\"b,1\n300,250\n", 1, 1},
+		{"300,250\n\"abc\n", 1, 2},
+	}
+	for _, tt := range tests {
+		result, err := csvimport.Parse(strings.NewReader(tt.input), 0)
+		if err != nil {
+			t.Fatalf("%q: 解析失败: %v", tt.input, err)
+		}
+		if len(result.Points) != tt.points {
+			t.Errorf("%q: 数据点 = %+v, 期望 %d 个", tt.input, result.Points, tt.points)
+		}
+		if len(result.Errors) != 1 || result.Errors[0].Row != tt.row {
+			t.Errorf("%q: 错误 = %+v, 期望第%d行出错", tt.input, result.Errors, tt.row)
+		}
+	}
+}
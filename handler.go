@@ -1,11 +1,22 @@
 package main
 
 import (
+	"bytes"
+	"fmt"
+	"io"
 	"log/slog"
+	"math"
 	"path/filepath"
 	"runtime/debug"
-	"slices"
+	"strings"
+	"time"
 
+	"github.com/Equationzhao/power/activity"
+	"github.com/Equationzhao/power/criticalpower"
+	"github.com/Equationzhao/power/csvimport"
+	"github.com/Equationzhao/power/physics"
+	"github.com/Equationzhao/power/pmc"
+	"github.com/Equationzhao/power/workout"
 	"github.com/bytedance/sonic"
 	"github.com/valyala/fasthttp"
 )
@@ -16,17 +27,40 @@ const (
 	methodNotAllowed    = `{"error": "Method Not Allowed"}`
 )
 
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// createErrorResponse 返回错误信息的 JSON，错误信息中可能含有引号等需要转义的字符
 func createErrorResponse(errMsg string) string {
-	return `{"error": "` + errMsg + `"}`
+	body, err := sonic.Marshal(map[string]string{"error": errMsg})
+	if err != nil {
+		return internalServerError
+	}
+	return string(body)
+}
+
+// recoverPanic 恢复处理请求时发生的 panic，需要直接 defer 调用
+func recoverPanic(ctx *fasthttp.RequestCtx, handler string) {
+	if r := recover(); r != nil {
+		slog.Error("panic in "+handler, "error", r, "trace", debug.Stack())
+		ctx.Error(internalServerError, fasthttp.StatusInternalServerError)
+	}
+}
+
+// writeJSON 将 v 序列化为 JSON 写入响应
+func writeJSON(ctx *fasthttp.RequestCtx, v any) {
+	respBytes, err := sonic.Marshal(v)
+	if err != nil {
+		ctx.Error(internalServerError, fasthttp.StatusInternalServerError)
+		return
+	}
+	ctx.Response.Header.Set("Content-Type", "application/json")
+	ctx.SetBody(respBytes)
 }
 
 func calculateHandler(ctx *fasthttp.RequestCtx) {
-	defer func() {
-		if r := recover(); r != nil {
-			slog.Error("panic in calculateHandler", "error", r, "trace", debug.Stack())
-			ctx.Error(internalServerError, fasthttp.StatusInternalServerError)
-		}
-	}()
+	defer recoverPanic(ctx, "calculateHandler")
 
 	if string(ctx.Method()) != "POST" {
 		ctx.Error(methodNotAllowed, fasthttp.StatusMethodNotAllowed)
@@ -38,108 +72,961 @@ func calculateHandler(ctx *fasthttp.RequestCtx) {
 		return
 	}
 	data.Normalize()
-	model, err := CalculateModel(ConvertPowerTimePointToCP(data.PT), data.Runtimes, data.OutlierDetect)
+	options, err := data.ModelOptions()
 	if err != nil {
-		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusInternalServerError)
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
 		return
 	}
-	tzBO := model.GetTrainingZones()
-
-	// 计算功率-时间曲线
-	var powerTimeCurve []PowerTimePoint
-	timeMap := make(map[float64]struct{})
-	for t := 1.0; t <= 7200; t *= 1.15 {
-		t = float64(int(t*10)) / 10
-		timeMap[t] = struct{}{}
+	scheme, err := data.TrainingZoneScheme()
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
 	}
-	// 插入固定的几个重要时间点
-	// 1s - 60s
-	// 60s 之后每隔 5s 一个点
-	// 2m 之后每隔 10s 一个点
-	// 5m 之后每隔 30s 一个点
-	// 10m 之后每隔 1min 一个点
-	// 1h 之后每隔 5min 一个点
-	// 生成代码
-	for i := 1; i <= 60; i++ {
-		timeMap[float64(i)] = struct{}{}
+	sport, err := data.SportType()
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
 	}
-	for i := 60; i <= 120; i += 5 {
-		timeMap[float64(i)] = struct{}{}
+	if sport != criticalpower.Cycling {
+		calculateSpeed(ctx, &data, sport, scheme, options)
+		return
 	}
-	for i := 120; i <= 300; i += 10 {
-		timeMap[float64(i)] = struct{}{}
+	model, err := CalculateModel(ConvertPowerTimePointToCP(data.PT), options...)
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusInternalServerError)
+		return
 	}
-	for i := 300; i <= 600; i += 30 {
-		timeMap[float64(i)] = struct{}{}
+	writeJSON(ctx, buildCalculateResponse(model, &data, scheme))
+}
+
+// calculateSpeed 拟合跑步与游泳的临界速度模型，数据点中的速度或距离代替功率
+func calculateSpeed(ctx *fasthttp.RequestCtx, data *CalculateRequest, sport criticalpower.Sport, scheme criticalpower.ZoneScheme, options []criticalpower.ModelOption) {
+	points, err := data.SpeedPoints()
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+	model := criticalpower.NewSpeed(sport, options...)
+	if err := model.Fit(points); err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusInternalServerError)
+		return
 	}
-	for i := 600; i <= 3600; i += 60 {
-		timeMap[float64(i)] = struct{}{}
+
+	distances := data.Distances
+	if len(distances) == 0 {
+		distances = sport.RaceDistances()
 	}
-	for i := 3600; i <= 7200; i += 300 {
-		timeMap[float64(i)] = struct{}{}
+	races := make([]RacePrediction, 0, len(distances))
+	for _, d := range distances {
+		t, err := model.PredictRaceTime(d)
+		if err != nil {
+			ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+			return
+		}
+		races = append(races, RacePrediction{Distance: d, Time: t, Speed: d / t, Pace: sport.Pace(d / t)})
 	}
-	for _, t := range data.PT {
-		timeMap[t.Time] = struct{}{}
+
+	times := curveTimes(model.Type(), data.PT)
+	curve := make([]SpeedTimePoint, len(times))
+	for i, t := range times {
+		curve[i] = SpeedTimePoint{Time: t, Speed: model.PredictSpeed(t)}
 	}
-	var times []float64
-	for t := range timeMap {
-		times = append(times, t)
+	valid := make([]SpeedTimePoint, 0)
+	outliers := make([]SpeedTimePoint, 0)
+	for i, p := range model.Data {
+		if _, ok := model.Outliers[i]; ok {
+			outliers = append(outliers, SpeedTimePoint{Time: p.Time, Speed: p.Speed})
+		} else {
+			valid = append(valid, SpeedTimePoint{Time: p.Time, Speed: p.Speed})
+		}
 	}
-	slices.Sort(times)
-	for _, t := range times {
-		powerTimeCurve = append(powerTimeCurve, PowerTimePoint{
-			Time:  t,
-			Power: model.PredictPower(t),
-		})
+	var anchors []SpeedTimePoint
+	for _, i := range model.Anchors {
+		p := model.Data[i]
+		anchors = append(anchors, SpeedTimePoint{Time: p.Time, Speed: p.Speed})
 	}
 
+	writeJSON(ctx, SpeedResponse{
+		Sport:          string(sport),
+		Model:          string(model.Type()),
+		Fitter:         string(model.Fitter()),
+		Params:         model.Params(),
+		CS:             model.CS,
+		Dprime:         model.Dprime,
+		Vmax:           model.Vmax,
+		Tau:            model.Tau,
+		A:              model.A,
+		RMSE:           model.RMSE,
+		PaceDistance:   sport.PaceDistance(),
+		CSPace:         sport.Pace(model.CS),
+		ZoneScheme:     scheme.Name,
+		PaceZones:      ConvertPaceZones(model.GetPaceZones(scheme)),
+		Races:          races,
+		SpeedTimeCurve: curve,
+		SpeedTimePoint: valid,
+		Outliers:       outliers,
+		Anchors:        anchors,
+	})
+}
+
+// buildCalculateResponse 根据拟合结果构造 /calculate 的响应
+func buildCalculateResponse(model *criticalpower.CriticalPowerModel, data *CalculateRequest, scheme criticalpower.ZoneScheme) CalculateResponse {
+	// 计算功率-时间曲线
+	times := curveTimes(model.Type(), data.PT)
+	powerTimeCurve := predictCurve(model, times)
+
 	outliers := make([]PowerTimePoint, 0)
 	powerTimePoint := make([]PowerTimePoint, 0)
 	for i, pt := range model.Data {
+		point := PowerTimePoint{
+			Time:     pt.Time,
+			Power:    pt.Power,
+			Censored: pt.Censored,
+		}
 		if _, ok := model.Outliers[i]; !ok {
-			powerTimePoint = append(powerTimePoint, PowerTimePoint{
-				Time:  pt.Time,
-				Power: pt.Power,
-			})
+			powerTimePoint = append(powerTimePoint, point)
 		} else {
-			outliers = append(outliers, PowerTimePoint{
-				Time:  pt.Time,
-				Power: pt.Power,
-			})
+			outliers = append(outliers, point)
 		}
 	}
 
 	resp := CalculateResponse{
-		CP:     model.CP,
-		Wprime: model.Wprime,
-		Pmax:   model.Pmax,
-		Tau:    model.Tau,
-		RMSE:   model.RMSE,
-		TrainingZones: TrainingZones{
-			RecoveryZone:      zone{Min: tzBO.RecoveryZone.Min, Max: tzBO.RecoveryZone.Max},
-			EnduranceZone:     zone{Min: tzBO.EnduranceZone.Min, Max: tzBO.EnduranceZone.Max},
-			TempoZone:         zone{Min: tzBO.TempoZone.Min, Max: tzBO.TempoZone.Max},
-			ThresholdZone:     zone{Min: tzBO.ThresholdZone.Min, Max: tzBO.ThresholdZone.Max},
-			VO2MaxZone:        zone{Min: tzBO.VO2MaxZone.Min, Max: tzBO.VO2MaxZone.Max},
-			AnaerobicZone:     zone{Min: tzBO.AnaerobicZone.Min, Max: tzBO.AnaerobicZone.Max},
-			NeuromuscularZone: zone{Min: tzBO.NeuromuscularZone.Min, Max: tzBO.NeuromuscularZone.Max},
-		},
+		Model:           string(model.Type()),
+		Fitter:          string(model.Fitter()),
+		Params:          model.Params(),
+		CP:              model.CP,
+		Wprime:          model.Wprime,
+		Pmax:            model.Pmax,
+		Tau:             model.Tau,
+		RMSE:            model.RMSE,
+		ZoneScheme:      scheme.Name,
+		TrainingZones:   ConvertZones(model.GetTrainingZones(scheme)),
 		PowerTimeCurve:  powerTimeCurve,
 		PowerTimePoint:  powerTimePoint,
 		Outliers:        outliers,
 		OutliersCount:   len(outliers),
 		OutliersPercent: float64(len(outliers)) / float64(len(data.PT)) * 100,
 	}
+	if c := model.Covariance; c != nil {
+		resp.Covariance = &covariance{
+			Params:           c.Names,
+			Matrix:           c.Matrix,
+			Correlation:      c.Correlation,
+			StdErr:           c.StdErr,
+			PoorlyIdentified: model.PoorlyIdentified(),
+		}
+	}
+	if b := model.Bootstrap; b != nil {
+		ci := make(map[string]interval, len(b.Intervals))
+		for name, iv := range b.Intervals {
+			ci[name] = interval{Lower: iv.Lower, Upper: iv.Upper}
+		}
+		resp.ParamsCI = ci
+		resp.CPCI = ptr(ci[criticalpower.ParamCP])
+		resp.WprimeCI = ptr(ci[criticalpower.ParamWprime])
+		resp.PmaxCI = ptr(ci[criticalpower.ParamPmax])
+		resp.TauCI = ptr(ci[criticalpower.ParamTau])
+
+		resp.PowerTimeBand = make([]PowerTimeBand, len(times))
+		for i, t := range times {
+			band := b.PredictionBand(t)
+			resp.PowerTimeBand[i] = PowerTimeBand{Time: t, Lower: band.Lower, Upper: band.Upper}
+		}
+	}
+	if p := model.Posterior; p != nil {
+		intervals := make(map[string]interval, len(p.Intervals))
+		for name, iv := range p.Intervals {
+			intervals[name] = interval{Lower: iv.Lower, Upper: iv.Upper}
+		}
+		priors := make(map[string]Prior, len(p.Priors))
+		for name, prior := range p.Priors {
+			priors[name] = Prior{Mean: prior.Mean, SD: prior.SD}
+		}
+		predictive := make([]PredictivePoint, len(times))
+		for i, t := range times {
+			credible, band := p.CredibleBand(t), p.PredictiveBand(t)
+			predictive[i] = PredictivePoint{
+				Time:          t,
+				Mean:          p.PredictiveMean(t),
+				CredibleLower: credible.Lower,
+				CredibleUpper: credible.Upper,
+				Lower:         band.Lower,
+				Upper:         band.Upper,
+			}
+		}
+		resp.Posterior = &posterior{
+			Samples:        p.Samples,
+			Chains:         p.Chains,
+			Level:          p.Level,
+			AcceptanceRate: p.AcceptanceRate,
+			Means:          p.Means,
+			Intervals:      intervals,
+			RHat:           p.RHat,
+			Sigma:          p.Sigma,
+			Priors:         priors,
+			Predictive:     predictive,
+		}
+	}
+	for _, i := range model.Anchors {
+		pt := model.Data[i]
+		resp.Anchors = append(resp.Anchors, PowerTimePoint{Time: pt.Time, Power: pt.Power})
+	}
+	if model.Type() == criticalpower.OmniDomain {
+		resp.A = model.A
+		resp.TCPmax = criticalpower.TCPmax
+	}
 	if data.Weight > 0 {
 		resp.VO2Max = model.PredictVO2Max(data.Weight)
 	}
-	respBytes, err := sonic.Marshal(resp)
+	return resp
+}
+
+func compareHandler(ctx *fasthttp.RequestCtx) {
+	defer recoverPanic(ctx, "compareHandler")
+
+	if string(ctx.Method()) != "POST" {
+		ctx.Error(methodNotAllowed, fasthttp.StatusMethodNotAllowed)
+		return
+	}
+	var data CompareRequest
+	if err := sonic.Unmarshal(ctx.PostBody(), &data); err != nil {
+		ctx.Error(badRequest, fasthttp.StatusBadRequest)
+		return
+	}
+	data.Normalize()
+	options, err := data.ModelOptions()
 	if err != nil {
-		ctx.Error(internalServerError, fasthttp.StatusInternalServerError)
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
 		return
 	}
-	ctx.Response.Header.Set("Content-Type", "application/json")
-	ctx.SetBody(respBytes)
+	modelTypes, err := data.ModelTypes()
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+
+	models := make([]criticalpower.Model, len(modelTypes))
+	for i, modelType := range modelTypes {
+		models[i] = criticalpower.New(append(options, criticalpower.WithModel(modelType))...)
+	}
+	results, err := criticalpower.Compare(ConvertPowerTimePointToCP(data.PT), models...)
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusInternalServerError)
+		return
+	}
+
+	resp := CompareResponse{
+		Results:        make([]ModelComparison, len(results)),
+		PowerTimePoint: data.PT,
+	}
+	for i, c := range results {
+		model := c.Model.(*criticalpower.CriticalPowerModel)
+		result := ModelComparison{Model: string(model.Type())}
+		if c.Err != nil {
+			result.Error = c.Err.Error()
+			resp.Results[i] = result
+			continue
+		}
+		result.Params = model.Params()
+		result.K = c.K
+		result.RMSE = c.RMSE
+		result.AIC = c.AIC
+		result.BIC = c.BIC
+		result.Weight = c.Weight
+		if !math.IsInf(c.AICc, 1) {
+			result.AICc = ptr(c.AICc)
+		}
+		result.PowerTimeCurve = predictCurve(model, curveTimes(model.Type(), data.PT))
+		resp.Results[i] = result
+	}
+	writeJSON(ctx, resp)
+}
+
+func wbalHandler(ctx *fasthttp.RequestCtx) {
+	defer recoverPanic(ctx, "wbalHandler")
+
+	if string(ctx.Method()) != "POST" {
+		ctx.Error(methodNotAllowed, fasthttp.StatusMethodNotAllowed)
+		return
+	}
+	var data WbalRequest
+	if err := sonic.Unmarshal(ctx.PostBody(), &data); err != nil {
+		ctx.Error(badRequest, fasthttp.StatusBadRequest)
+		return
+	}
+	data.Normalize()
+	method, err := criticalpower.ParseWbalMethod(data.Method)
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+	model, err := data.Model()
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+	result, err := model.Wbal(data.Power, method)
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+
+	resp := WbalResponse{
+		Method:  string(method),
+		Params:  model.Params(),
+		CP:      model.CP,
+		Wprime:  model.Wprime,
+		Balance: result.Balance,
+		Min:     result.Min,
+		MinTime: result.MinTime,
+	}
+	if result.ExhaustedAt >= 0 {
+		resp.ExhaustedAt = ptr(result.ExhaustedAt)
+	}
+	writeJSON(ctx, resp)
+}
+
+func threeMinuteTestHandler(ctx *fasthttp.RequestCtx) {
+	defer recoverPanic(ctx, "threeMinuteTestHandler")
+
+	if string(ctx.Method()) != "POST" {
+		ctx.Error(methodNotAllowed, fasthttp.StatusMethodNotAllowed)
+		return
+	}
+	var data ThreeMinuteTestRequest
+	if err := sonic.Unmarshal(ctx.PostBody(), &data); err != nil {
+		ctx.Error(badRequest, fasthttp.StatusBadRequest)
+		return
+	}
+	data.Normalize()
+	scheme, err := zoneScheme(data.ZoneScheme, data.Zones)
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+	test, err := criticalpower.AnalyzeThreeMinuteTest(data.Power)
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+
+	model := test.Model()
+	flags := make([]string, len(test.Flags))
+	for i, flag := range test.Flags {
+		flags[i] = string(flag)
+	}
+	writeJSON(ctx, ThreeMinuteTestResponse{
+		Model:         string(model.Type()),
+		Params:        model.Params(),
+		CP:            model.CP,
+		Wprime:        model.Wprime,
+		Pmax:          model.Pmax,
+		EndPower:      test.EndPower,
+		WorkAboveEP:   test.WorkAboveEP,
+		PeakPower:     test.PeakPower,
+		PeakTime:      test.PeakTime,
+		Start:         test.Start,
+		Valid:         test.Valid(),
+		Flags:         flags,
+		ZoneScheme:    scheme.Name,
+		TrainingZones: ConvertZones(model.GetTrainingZones(scheme)),
+	})
+}
+
+func rampTestHandler(ctx *fasthttp.RequestCtx) {
+	defer recoverPanic(ctx, "rampTestHandler")
+
+	if string(ctx.Method()) != "POST" {
+		ctx.Error(methodNotAllowed, fasthttp.StatusMethodNotAllowed)
+		return
+	}
+	var data RampTestRequest
+	if err := sonic.Unmarshal(ctx.PostBody(), &data); err != nil {
+		ctx.Error(badRequest, fasthttp.StatusBadRequest)
+		return
+	}
+	data.Normalize()
+	rampOptions, err := data.RampOptions()
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+	test, err := criticalpower.AnalyzeRampTest(data.Power, rampOptions...)
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+
+	resp := RampTestResponse{
+		Steps:         ConvertRampSteps(test.Steps),
+		StepDuration:  test.StepDuration,
+		StepIncrement: test.StepIncrement,
+		LastMinute:    test.LastMinute,
+		Best60:        test.Best60,
+		MAPMethod:     string(test.Method),
+		MAP:           test.MAP,
+		CPRatio:       test.CPRatio,
+		CP:            test.CP,
+		CPPrior:       Prior{Mean: test.CPPrior().Mean, SD: test.CPPrior().SD},
+	}
+	if len(data.PT) > 0 {
+		options, err := data.ModelOptions()
+		if err != nil {
+			ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+			return
+		}
+		scheme, err := data.TrainingZoneScheme()
+		if err != nil {
+			ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+			return
+		}
+		model := criticalpower.New(options...)
+		prior, err := test.FitWithPrior(model, ConvertPowerTimePointToCP(data.PT))
+		if err != nil {
+			ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusInternalServerError)
+			return
+		}
+		if prior != nil {
+			resp.Prior = &PowerTimePoint{Time: prior.Time, Power: prior.Power}
+			data.PT = append(data.PT, *resp.Prior)
+		}
+		resp.Fit = ptr(buildCalculateResponse(model, &data.CalculateRequest, scheme))
+	}
+	writeJSON(ctx, resp)
+}
+
+func intervalsHandler(ctx *fasthttp.RequestCtx) {
+	defer recoverPanic(ctx, "intervalsHandler")
+
+	if string(ctx.Method()) != "POST" {
+		ctx.Error(methodNotAllowed, fasthttp.StatusMethodNotAllowed)
+		return
+	}
+	var data IntervalRequest
+	if err := sonic.Unmarshal(ctx.PostBody(), &data); err != nil {
+		ctx.Error(badRequest, fasthttp.StatusBadRequest)
+		return
+	}
+	data.Normalize()
+	if err := data.Validate(); err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+	method, err := criticalpower.ParseWbalMethod(data.Method)
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+	model, err := data.Model()
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+	session := data.Session(model.CP)
+	result, err := model.SimulateIntervals(session, method)
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+
+	resp := IntervalResponse{
+		Method:        string(method),
+		Params:        model.Params(),
+		CP:            model.CP,
+		Wprime:        model.Wprime,
+		WorkPower:     session.WorkPower,
+		RestPower:     session.RestPower,
+		Feasible:      result.Feasible,
+		CompletedReps: result.CompletedReps,
+		MaxWorkPower:  result.MaxWorkPower,
+		Balance:       result.Balance,
+		MinBalance:    result.MinBalance,
+	}
+	if result.ExhaustedAt >= 0 {
+		resp.ExhaustedAt = ptr(result.ExhaustedAt)
+		resp.ExhaustedRep = ptr(result.ExhaustedRep)
+	}
+	writeJSON(ctx, resp)
+}
+
+// courseHandler 预测路线的用时，没有给出功率时根据模型计算最佳用时
+func courseHandler(ctx *fasthttp.RequestCtx) {
+	defer recoverPanic(ctx, "courseHandler")
+
+	if string(ctx.Method()) != "POST" {
+		ctx.Error(methodNotAllowed, fasthttp.StatusMethodNotAllowed)
+		return
+	}
+	var data CourseRequest
+	if err := sonic.Unmarshal(ctx.PostBody(), &data); err != nil {
+		ctx.Error(badRequest, fasthttp.StatusBadRequest)
+		return
+	}
+	data.Normalize()
+	if err := data.Validate(); err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+	rider, course := data.Rider(), data.Course()
+
+	var resp CourseResponse
+	var result *physics.Result
+	var err error
+	if data.Power > 0 {
+		result, err = rider.RideConstant(course, data.Power)
+	} else {
+		var model *criticalpower.CriticalPowerModel
+		if model, err = data.Model(); err == nil {
+			resp.Params = model.Params()
+			result, err = rider.BestTime(course, model)
+		}
+	}
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+	if result.Time > maxSessionDuration {
+		ctx.Error(createErrorResponse(fmt.Sprintf("路线用时不能超过 %d 秒", maxSessionDuration)), fasthttp.StatusBadRequest)
+		return
+	}
+
+	resp.Power = result.AveragePower
+	resp.Time = result.Time
+	resp.Distance = result.Distance
+	resp.Climbing = course.Climbing()
+	resp.AverageSpeed = result.AverageSpeed
+	resp.Splits = make([]CourseSplit, len(result.Splits))
+	for i, s := range result.Splits {
+		resp.Splits[i] = CourseSplit{Start: s.Start, Distance: s.Distance, Grade: s.Grade, Power: s.Power, Speed: s.Speed, Time: s.Time}
+	}
+	writeJSON(ctx, resp)
+}
+
+// workoutHandler 导出训练课程文件
+func workoutHandler(ctx *fasthttp.RequestCtx) {
+	defer recoverPanic(ctx, "workoutHandler")
+
+	if string(ctx.Method()) != "POST" {
+		ctx.Error(methodNotAllowed, fasthttp.StatusMethodNotAllowed)
+		return
+	}
+	var data WorkoutRequest
+	if err := sonic.Unmarshal(ctx.PostBody(), &data); err != nil {
+		ctx.Error(badRequest, fasthttp.StatusBadRequest)
+		return
+	}
+	data.Normalize()
+	if data.Format == "" {
+		data.Format = string(workout.ZWO)
+	}
+	format, err := workout.ParseFormat(data.Format)
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+	model, err := data.Model()
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := workout.Write(&buf, data.Workout(), model.CP, format); err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+	ctx.Response.Header.Set("Content-Type", format.ContentType())
+	ctx.Response.Header.Set("Content-Disposition", `attachment; filename="`+workoutFileName(data.Name)+"."+string(format)+`"`)
+	ctx.SetBody(buf.Bytes())
+}
+
+// workoutFileName 将课程名转换为安全的文件名，只保留字母、数字、下划线与连字符
+func workoutFileName(name string) string {
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		case r == ' ':
+			return '_'
+		default:
+			return -1
+		}
+	}, name)
+	if safe == "" {
+		return "workout"
+	}
+	return safe
+}
+
+func pacingHandler(ctx *fasthttp.RequestCtx) {
+	defer recoverPanic(ctx, "pacingHandler")
+
+	if string(ctx.Method()) != "POST" {
+		ctx.Error(methodNotAllowed, fasthttp.StatusMethodNotAllowed)
+		return
+	}
+	var data PacingRequest
+	if err := sonic.Unmarshal(ctx.PostBody(), &data); err != nil {
+		ctx.Error(badRequest, fasthttp.StatusBadRequest)
+		return
+	}
+	data.Normalize()
+	model, err := data.Model()
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+	if err := data.Validate(model.CP); err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+	plan, err := model.PlanPacing(data.PacingSegments(), data.SpeedReference())
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+
+	resp := PacingResponse{
+		Params:       model.Params(),
+		CP:           model.CP,
+		Wprime:       model.Wprime,
+		Duration:     plan.Duration,
+		Distance:     plan.Distance,
+		AveragePower: plan.AveragePower,
+		Segments:     make([]PlannedSegment, len(plan.Segments)),
+		Balance:      plan.Balance,
+		MinBalance:   plan.MinBalance,
+		MinTime:      plan.MinTime,
+	}
+	for i, s := range plan.Segments {
+		resp.Segments[i] = PlannedSegment{Start: s.Start, Duration: s.Duration, Distance: s.Distance, Power: s.Power}
+	}
+	writeJSON(ctx, resp)
+}
+
+func loadHandler(ctx *fasthttp.RequestCtx) {
+	defer recoverPanic(ctx, "loadHandler")
+
+	if string(ctx.Method()) != "POST" {
+		ctx.Error(methodNotAllowed, fasthttp.StatusMethodNotAllowed)
+		return
+	}
+	var data LoadRequest
+	if err := sonic.Unmarshal(ctx.PostBody(), &data); err != nil {
+		ctx.Error(badRequest, fasthttp.StatusBadRequest)
+		return
+	}
+	data.Normalize()
+	model, err := data.Model()
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+	load, err := model.TrainingLoad(data.Power)
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+
+	writeJSON(ctx, LoadResponse{
+		Params:           model.Params(),
+		CP:               model.CP,
+		Duration:         load.Duration,
+		Work:             load.Work,
+		AveragePower:     load.AveragePower,
+		NormalizedPower:  load.NormalizedPower,
+		IntensityFactor:  load.IntensityFactor,
+		TSS:              load.TSS,
+		VariabilityIndex: load.VariabilityIndex,
+	})
+}
+
+func pmcHandler(ctx *fasthttp.RequestCtx) {
+	defer recoverPanic(ctx, "pmcHandler")
+
+	if string(ctx.Method()) != "POST" {
+		ctx.Error(methodNotAllowed, fasthttp.StatusMethodNotAllowed)
+		return
+	}
+	var data PMCRequest
+	if err := sonic.Unmarshal(ctx.PostBody(), &data); err != nil {
+		ctx.Error(badRequest, fasthttp.StatusBadRequest)
+		return
+	}
+	history, err := ConvertDailyLoads(data.History)
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+	planned, err := ConvertDailyLoads(data.Planned)
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+	chart := pmc.New(data.Options()...)
+	days, err := chart.Compute(history, planned)
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+
+	writeJSON(ctx, PMCResponse{
+		CTLDays: chart.CTLDays(),
+		ATLDays: chart.ATLDays(),
+		Days:    ConvertPMCDays(days),
+	})
+}
+
+func mmpHandler(ctx *fasthttp.RequestCtx) {
+	defer recoverPanic(ctx, "mmpHandler")
+
+	if string(ctx.Method()) != "POST" {
+		ctx.Error(methodNotAllowed, fasthttp.StatusMethodNotAllowed)
+		return
+	}
+	var data MMPRequest
+	if err := sonic.Unmarshal(ctx.PostBody(), &data); err != nil {
+		ctx.Error(badRequest, fasthttp.StatusBadRequest)
+		return
+	}
+	if len(data.Power) == 0 {
+		ctx.Error(createErrorResponse("功率流为空"), fasthttp.StatusBadRequest)
+		return
+	}
+	// 与导入运动记录的时长上限一致
+	if len(data.Power) > int(activity.MaxDuration/time.Second) {
+		ctx.Error(createErrorResponse("功率流时长不能超过 48 小时"), fasthttp.StatusBadRequest)
+		return
+	}
+
+	var points []criticalpower.PowerTimePoint
+	if data.All {
+		mmp := criticalpower.MeanMaximalPower(data.Power)
+		points = make([]criticalpower.PowerTimePoint, len(mmp))
+		for i, p := range mmp {
+			points[i] = criticalpower.PowerTimePoint{Time: float64(i + 1), Power: p}
+		}
+	} else {
+		points = criticalpower.MeanMaximalPoints(data.Power, data.Durations)
+	}
+	for i := range points {
+		points[i].Censored = data.Censored
+	}
+	writeJSON(ctx, MMPResponse{PT: ConvertCPToPowerTimePoint(points)})
+}
+
+// uploadedFile 读取上传的文件，支持 multipart 表单中的 file 字段或直接作为请求体
+func uploadedFile(ctx *fasthttp.RequestCtx) ([]byte, error) {
+	if !bytes.HasPrefix(ctx.Request.Header.ContentType(), []byte("multipart/form-data")) {
+		return ctx.PostBody(), nil
+	}
+	header, err := ctx.FormFile("file")
+	if err != nil {
+		return nil, err
+	}
+	f, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// calculateRequestFromQuery 从查询参数中读取拟合选项，用于请求体为文件的接口
+func calculateRequestFromQuery(args *fasthttp.Args) CalculateRequest {
+	return CalculateRequest{
+		Runtimes:      args.GetUintOrZero("runtimes"),
+		Weight:        args.GetUfloatOrZero("weight"),
+		OutlierDetect: args.GetBool("outlier_detect"),
+		Model:         string(args.Peek("model")),
+		Fitter:        string(args.Peek("fitter")),
+		Bootstrap:     args.GetUintOrZero("bootstrap"),
+		ZoneScheme:    string(args.Peek("zone_scheme")),
+	}
+}
+
+// uploadedActivity 解析上传的运动记录文件并重采样为 1 Hz 数据流，format 为空时根据文件内容判断格式
+func uploadedActivity(ctx *fasthttp.RequestCtx, format activity.Format) (*activity.Stream, error) {
+	body, err := uploadedFile(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if format == "" {
+		if format, err = activity.DetectFormat(body); err != nil {
+			return nil, err
+		}
+	}
+	records, err := activity.Decode(bytes.NewReader(body), format)
+	if err != nil {
+		return nil, err
+	}
+	return activity.Resample(records)
+}
+
+// modelRequestFromQuery 从查询参数中读取模型选项与模型参数，参数名与 params 中的相同
+func modelRequestFromQuery(args *fasthttp.Args) ModelRequest {
+	req := ModelRequest{CalculateRequest: calculateRequestFromQuery(args)}
+	for _, name := range []string{
+		criticalpower.ParamCP, criticalpower.ParamWprime, criticalpower.ParamPmax,
+		criticalpower.ParamTau, criticalpower.ParamA,
+	} {
+		if value, err := args.GetUfloat(name); err == nil {
+			if req.Params == nil {
+				req.Params = make(map[string]float64)
+			}
+			req.Params[name] = value
+		}
+	}
+	return req
+}
+
+// importActivity 从运动记录中提取平均最大功率曲线并拟合模型
+func importActivity(ctx *fasthttp.RequestCtx, stream *activity.Stream) {
+	points := MeanMaximalPoints(stream)
+	if len(points) == 0 {
+		ctx.Error(createErrorResponse("运动记录中没有功率数据"), fasthttp.StatusBadRequest)
+		return
+	}
+
+	data := calculateRequestFromQuery(ctx.QueryArgs())
+	data.PT = ConvertCPToPowerTimePoint(points)
+	data.Normalize()
+	options, err := data.ModelOptions()
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+	scheme, err := data.TrainingZoneScheme()
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+	model, err := CalculateModel(points, options...)
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(ctx, ImportResponse{
+		CalculateResponse: buildCalculateResponse(model, &data, scheme),
+		Activity:          summarizeStream(stream),
+	})
+}
+
+// importHandler 导入运动记录文件，format 为空时根据文件内容判断格式
+func importHandler(ctx *fasthttp.RequestCtx, format activity.Format) {
+	defer recoverPanic(ctx, "importHandler")
+
+	if string(ctx.Method()) != "POST" {
+		ctx.Error(methodNotAllowed, fasthttp.StatusMethodNotAllowed)
+		return
+	}
+	stream, err := uploadedActivity(ctx, format)
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+	importActivity(ctx, stream)
+}
+
+// timeInZoneHandler 统计上传的运动记录在各训练区间内的时间
+// 查询参数中给出模型参数（cp、wprime 等）时直接使用，否则根据运动记录的平均最大功率曲线拟合模型
+func timeInZoneHandler(ctx *fasthttp.RequestCtx) {
+	defer recoverPanic(ctx, "timeInZoneHandler")
+
+	if string(ctx.Method()) != "POST" {
+		ctx.Error(methodNotAllowed, fasthttp.StatusMethodNotAllowed)
+		return
+	}
+	stream, err := uploadedActivity(ctx, activity.Format(ctx.QueryArgs().Peek("format")))
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+
+	data := modelRequestFromQuery(ctx.QueryArgs())
+	var warning string
+	if len(data.Params) == 0 {
+		// 一次骑行的平均最大功率大多不是全力的，拟合出的 CP 偏低，区间边界只能作为参考
+		data.PT = ConvertCPToPowerTimePoint(MeanMaximalPoints(stream))
+		warning = rideParamsWarning
+	}
+	data.Normalize()
+	scheme, err := data.TrainingZoneScheme()
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+	model, err := data.Model()
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+	smoothing := ctx.QueryArgs().GetUintOrZero("smoothing")
+	zones, err := criticalpower.TimeInZones(stream.Power, model.GetTrainingZones(scheme), smoothing)
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+
+	writeJSON(ctx, TimeInZoneResponse{
+		ZoneScheme: scheme.Name,
+		Smoothing:  smoothing,
+		Params:     model.Params(),
+		Activity:   summarizeStream(stream),
+		Zones:      ConvertZoneTimes(zones),
+		Warning:    warning,
+	})
+}
+
+// importCSVHandler 导入功率-时间 CSV/TSV 并拟合模型，体重等选项通过查询参数传入
+func importCSVHandler(ctx *fasthttp.RequestCtx) {
+	defer recoverPanic(ctx, "importCSVHandler")
+
+	if string(ctx.Method()) != "POST" {
+		ctx.Error(methodNotAllowed, fasthttp.StatusMethodNotAllowed)
+		return
+	}
+	body, err := uploadedFile(ctx)
+	if err != nil {
+		ctx.Error(badRequest, fasthttp.StatusBadRequest)
+		return
+	}
+	data := calculateRequestFromQuery(ctx.QueryArgs())
+	parsed, err := csvimport.Parse(bytes.NewReader(body), data.Weight)
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+	rowErrors := ConvertRowErrors(parsed.Errors)
+	if len(parsed.Points) == 0 {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		writeJSON(ctx, CSVImportError{Error: "CSV 中没有有效的数据", Errors: rowErrors})
+		return
+	}
+
+	data.PT = ConvertCPToPowerTimePoint(parsed.Points)
+	data.Normalize()
+	options, err := data.ModelOptions()
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+	scheme, err := data.TrainingZoneScheme()
+	if err != nil {
+		ctx.Error(createErrorResponse(err.Error()), fasthttp.StatusBadRequest)
+		return
+	}
+	model, err := CalculateModel(ConvertPowerTimePointToCP(data.PT), options...)
+	if err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		writeJSON(ctx, CSVImportError{Error: err.Error(), Errors: rowErrors})
+		return
+	}
+	writeJSON(ctx, CSVImportResponse{
+		CalculateResponse: buildCalculateResponse(model, &data, scheme),
+		Errors:            rowErrors,
+	})
 }
 
 func mainHandler(ctx *fasthttp.RequestCtx) {
@@ -157,6 +1044,57 @@ func mainHandler(ctx *fasthttp.RequestCtx) {
 	case path == "/calculate":
 		calculateHandler(ctx)
 
+	case path == "/compare":
+		compareHandler(ctx)
+
+	case path == "/wbal":
+		wbalHandler(ctx)
+
+	case path == "/3mt":
+		threeMinuteTestHandler(ctx)
+
+	case path == "/ramp":
+		rampTestHandler(ctx)
+
+	case path == "/intervals":
+		intervalsHandler(ctx)
+
+	case path == "/course":
+		courseHandler(ctx)
+
+	case path == "/workout":
+		workoutHandler(ctx)
+
+	case path == "/pacing":
+		pacingHandler(ctx)
+
+	case path == "/load":
+		loadHandler(ctx)
+
+	case path == "/pmc":
+		pmcHandler(ctx)
+
+	case path == "/mmp":
+		mmpHandler(ctx)
+
+	case path == "/import":
+		importHandler(ctx, "")
+
+	case path == "/import/fit":
+		importHandler(ctx, activity.FIT)
+
+	case path == "/import/tcx":
+		importHandler(ctx, activity.TCX)
+
+	case path == "/import/gpx":
+		importHandler(ctx, activity.GPX)
+
+	case path == "/import/csv":
+		importCSVHandler(ctx)
+
+	case path == "/time-in-zone":
+		timeInZoneHandler(ctx)
+
 	case path == "/favicon.ico":
 		staticFilePath := filepath.Join("static", "favicon.ico")
 		if fileExists(staticFilePath) {
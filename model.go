@@ -1,18 +1,46 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"slices"
+	"time"
 
 	"github.com/Equationzhao/power/criticalpower"
+	"github.com/Equationzhao/power/csvimport"
+	"github.com/Equationzhao/power/physics"
+	"github.com/Equationzhao/power/pmc"
+	"github.com/Equationzhao/power/workout"
 )
 
-const maxRuntimes = 1000000
+const (
+	maxRuntimes        = 1000000
+	maxBootstrap       = 2000
+	maxSessionDuration = 24 * 3600 // 按秒模拟的间歇训练与配速计划的最长时长（秒）
+	maxPacingSegments  = 1000
+	maxCoursePoints    = 10000
+)
 
 type CalculateRequest struct {
 	PT            []PowerTimePoint `json:"pt"`
 	Runtimes      int              `json:"runtimes"`
 	Weight        float64          `json:"weight"`
 	OutlierDetect bool             `json:"outlier_detect"`
+	Model         string           `json:"model"`
+	Fitter        string           `json:"fitter"`
+	Bootstrap     int              `json:"bootstrap"`   // 自助法重抽样次数，0 表示不计算置信区间
+	ZoneScheme    string           `json:"zone_scheme"` // 训练区间方案：coggan、seiler 或 polarized
+	Zones         []ZoneDefinition `json:"zones"`       // 自定义训练区间，给出时忽略 zone_scheme
+	Sport         string           `json:"sport"`       // cycling、running 或 swimming
+	Distances     []float64        `json:"distances"`   // 跑步与游泳预测成绩的距离（米），为空时使用常见比赛距离
+	Priors        map[string]Prior `json:"priors"`      // 贝叶斯拟合的先验，为空时使用按体重缩放的人群先验
+}
+
+// Prior 参数的正态先验
+type Prior struct {
+	Mean float64 `json:"mean"`
+	SD   float64 `json:"sd"`
 }
 
 func (req *CalculateRequest) Normalize() {
@@ -29,19 +57,153 @@ func (req *CalculateRequest) Normalize() {
 	if req.Weight <= 0 {
 		req.Weight = 0.0
 	}
+
+	if req.Bootstrap < 0 {
+		req.Bootstrap = 0
+	} else if req.Bootstrap > maxBootstrap {
+		req.Bootstrap = maxBootstrap
+	}
+
+	if req.Model == "" {
+		req.Model = string(criticalpower.DefaultModelType)
+	}
+
+	if req.Fitter == "" {
+		req.Fitter = string(criticalpower.DefaultFitter)
+	}
+
+	if req.Sport == "" {
+		req.Sport = string(criticalpower.DefaultSport)
+	}
+
+	if req.ZoneScheme == "" {
+		req.ZoneScheme = criticalpower.DefaultZoneScheme
+		if req.Sport != string(criticalpower.Cycling) {
+			req.ZoneScheme = criticalpower.DefaultPaceZoneScheme
+		}
+	}
+}
+
+// SportType 返回请求的运动项目
+func (req *CalculateRequest) SportType() (criticalpower.Sport, error) {
+	return criticalpower.ParseSport(req.Sport)
+}
+
+// SpeedPoints 将跑步与游泳的数据点换算为速度-时间数据，每个点需要给出 speed 或 distance
+func (req *CalculateRequest) SpeedPoints() ([]criticalpower.SpeedTimePoint, error) {
+	points := make([]criticalpower.SpeedTimePoint, len(req.PT))
+	for i, p := range req.PT {
+		if p.Time <= 0 {
+			return nil, fmt.Errorf("第%d个点的时间必须大于0", i+1)
+		}
+		speed := p.Speed
+		if speed <= 0 && p.Distance > 0 {
+			speed = p.Distance / p.Time
+		}
+		if speed <= 0 {
+			return nil, fmt.Errorf("第%d个点需要给出 speed 或 distance", i+1)
+		}
+		points[i] = criticalpower.SpeedTimePoint{Time: p.Time, Speed: speed}
+	}
+	return points, nil
+}
+
+// TrainingZoneScheme 返回请求的训练区间方案
+func (req *CalculateRequest) TrainingZoneScheme() (criticalpower.ZoneScheme, error) {
+	return zoneScheme(req.ZoneScheme, req.Zones)
+}
+
+// customZoneScheme 自定义训练区间方案的名称
+const customZoneScheme = "custom"
+
+// ZoneBound 区间边界，anchor 为 cp、pmax 或 wprime
+type ZoneBound struct {
+	Anchor string  `json:"anchor"`
+	Value  float64 `json:"value"` // 基准为 cp 或 pmax 时为比例，基准为 wprime 时为耗尽时间（秒）
+}
+
+// ZoneDefinition 自定义训练区间
+type ZoneDefinition struct {
+	Name string    `json:"name"`
+	Min  ZoneBound `json:"min"`
+	Max  ZoneBound `json:"max"`
+}
+
+// zoneScheme 给出自定义区间时校验并返回自定义方案，否则按名称返回内置方案
+func zoneScheme(name string, zones []ZoneDefinition) (criticalpower.ZoneScheme, error) {
+	if len(zones) == 0 {
+		return criticalpower.ParseZoneScheme(name)
+	}
+	bound := func(b ZoneBound) criticalpower.ZoneBound {
+		return criticalpower.ZoneBound{Anchor: criticalpower.ZoneAnchor(b.Anchor), Value: b.Value}
+	}
+	scheme := criticalpower.ZoneScheme{Name: customZoneScheme, Zones: make([]criticalpower.ZoneDefinition, len(zones))}
+	for i, z := range zones {
+		scheme.Zones[i] = criticalpower.ZoneDefinition{Name: z.Name, Min: bound(z.Min), Max: bound(z.Max)}
+	}
+	if err := scheme.Validate(); err != nil {
+		return criticalpower.ZoneScheme{}, err
+	}
+	return scheme, nil
+}
+
+// ModelOptions 根据请求构造模型选项
+func (req *CalculateRequest) ModelOptions() ([]criticalpower.ModelOption, error) {
+	modelType, err := criticalpower.ParseModelType(req.Model)
+	if err != nil {
+		return nil, err
+	}
+	fitter, err := criticalpower.ParseFitter(req.Fitter)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []criticalpower.ModelOption{
+		criticalpower.WithModel(modelType),
+		criticalpower.WithFitter(fitter),
+		criticalpower.WithRunTimes(req.Runtimes),
+	}
+	if req.OutlierDetect {
+		options = append(options, criticalpower.WithOutlierDetect())
+	}
+	if req.Bootstrap > 0 {
+		if fitter == criticalpower.Bayesian || fitter == criticalpower.Envelope {
+			return nil, fmt.Errorf("%s 拟合算法不支持 bootstrap", fitter)
+		}
+		options = append(options, criticalpower.WithBootstrap(req.Bootstrap))
+	}
+	if fitter == criticalpower.Bayesian {
+		if req.Sport != "" && req.Sport != string(criticalpower.Cycling) {
+			return nil, errors.New("贝叶斯拟合目前只支持骑行")
+		}
+		// 默认先验在拟合时按模型类型生成，/compare 替换模型类型后仍然使用对应模型的先验
+		priors := make(criticalpower.Priors, len(req.Priors))
+		for name, p := range req.Priors {
+			priors[name] = criticalpower.Prior{Mean: p.Mean, SD: p.SD}
+		}
+		if err := priors.Validate(); err != nil {
+			return nil, err
+		}
+		options = append(options, criticalpower.WithPriorMass(req.Weight), criticalpower.WithPriors(priors))
+	}
+	return options, nil
 }
 
 type PowerTimePoint struct {
-	Time  float64 `json:"time"`
-	Power float64 `json:"power"`
+	Time     float64 `json:"time"`
+	Power    float64 `json:"power"`
+	Speed    float64 `json:"speed,omitempty"`    // 跑步与游泳的平均速度（m/s）
+	Distance float64 `json:"distance,omitempty"` // 跑步与游泳在 time 内完成的距离（米），未给出 speed 时使用
+	Censored bool    `json:"censored,omitempty"` // 次最大努力，只说明真实能力不低于该功率
 }
 
 func ConvertPowerTimePointToCP(pt []PowerTimePoint) []criticalpower.PowerTimePoint {
 	cp := make([]criticalpower.PowerTimePoint, len(pt))
 	for i, p := range pt {
 		cp[i] = criticalpower.PowerTimePoint{
-			Time:  p.Time,
-			Power: p.Power,
+			Time:     p.Time,
+			Power:    p.Power,
+			Censored: p.Censored,
 		}
 	}
 	return cp
@@ -51,22 +213,46 @@ func ConvertCPToPowerTimePoint(cp []criticalpower.PowerTimePoint) []PowerTimePoi
 	pt := make([]PowerTimePoint, len(cp))
 	for i, p := range cp {
 		pt[i] = PowerTimePoint{
-			Time:  p.Time,
-			Power: p.Power,
+			Time:     p.Time,
+			Power:    p.Power,
+			Censored: p.Censored,
 		}
 	}
 	return pt
 }
 
 type CalculateResponse struct {
-	CP             float64          `json:"cp"`
-	Wprime         float64          `json:"wprime"`
-	Pmax           float64          `json:"pmax"`
-	Tau            float64          `json:"tau"`
-	RMSE           float64          `json:"rmse"`
-	VO2Max         float64          `json:"vo2max"`
-	TrainingZones  TrainingZones    `json:"training_zones"`
-	PowerTimeCurve []PowerTimePoint `json:"power_time_curve"`
+	Model          string             `json:"model"`
+	Fitter         string             `json:"fitter"`
+	Params         map[string]float64 `json:"params"`
+	CP             float64            `json:"cp"`
+	Wprime         float64            `json:"wprime"`
+	Pmax           float64            `json:"pmax"`
+	Tau            float64            `json:"tau"`
+	A              float64            `json:"a,omitempty"`
+	TCPmax         float64            `json:"tcpmax,omitempty"`
+	RMSE           float64            `json:"rmse"`
+	VO2Max         float64            `json:"vo2max"`
+	ZoneScheme     string             `json:"zone_scheme"`
+	TrainingZones  []TrainingZone     `json:"training_zones"`
+	PowerTimeCurve []PowerTimePoint   `json:"power_time_curve"`
+
+	// 参数协方差，数据点不足以估计时为空
+	Covariance *covariance `json:"covariance,omitempty"`
+
+	// 自助法置信区间，仅在请求 bootstrap > 0 时返回
+	CPCI          *interval           `json:"cp_ci,omitempty"`
+	WprimeCI      *interval           `json:"wprime_ci,omitempty"`
+	PmaxCI        *interval           `json:"pmax_ci,omitempty"`
+	TauCI         *interval           `json:"tau_ci,omitempty"`
+	ParamsCI      map[string]interval `json:"params_ci,omitempty"`
+	PowerTimeBand []PowerTimeBand     `json:"power_time_band,omitempty"`
+
+	// 后验分布，仅在使用贝叶斯拟合时返回
+	Posterior *posterior `json:"posterior,omitempty"`
+
+	// 锚点，仅在使用包络拟合时返回
+	Anchors []PowerTimePoint `json:"anchors,omitempty"`
 
 	PowerTimePoint  []PowerTimePoint `json:"power_time_point"`
 	Outliers        []PowerTimePoint `json:"outliers"`
@@ -74,17 +260,751 @@ type CalculateResponse struct {
 	OutliersPercent float64          `json:"outliers_percent"`
 }
 
-type zone struct {
-	Min float64 `json:"min"`
-	Max float64 `json:"max"`
+// SpeedTimePoint 速度-时间数据点
+type SpeedTimePoint struct {
+	Time  float64 `json:"time"`
+	Speed float64 `json:"speed"`
+}
+
+// PaceZone 配速区间，配速单位为秒/pace_distance 米，无界时为 null
+type PaceZone struct {
+	Name     string   `json:"name"`
+	MinSpeed float64  `json:"min_speed"`
+	MaxSpeed float64  `json:"max_speed"`
+	SlowPace *float64 `json:"slow_pace"`
+	FastPace *float64 `json:"fast_pace"`
+}
+
+// RacePrediction 比赛成绩预测
+type RacePrediction struct {
+	Distance float64 `json:"distance"` // 米
+	Time     float64 `json:"time"`     // 秒
+	Speed    float64 `json:"speed"`    // m/s
+	Pace     float64 `json:"pace"`     // 秒/pace_distance 米
+}
+
+// SpeedResponse 跑步与游泳的临界速度拟合结果
+type SpeedResponse struct {
+	Sport          string             `json:"sport"`
+	Model          string             `json:"model"`
+	Fitter         string             `json:"fitter"`
+	Params         map[string]float64 `json:"params"`
+	CS             float64            `json:"cs"`
+	Dprime         float64            `json:"dprime"`
+	Vmax           float64            `json:"vmax"`
+	Tau            float64            `json:"tau"`
+	A              float64            `json:"a,omitempty"`
+	RMSE           float64            `json:"rmse"`
+	PaceDistance   float64            `json:"pace_distance"`
+	CSPace         float64            `json:"cs_pace"`
+	ZoneScheme     string             `json:"zone_scheme"`
+	PaceZones      []PaceZone         `json:"pace_zones"`
+	Races          []RacePrediction   `json:"races"`
+	SpeedTimeCurve []SpeedTimePoint   `json:"speed_time_curve"`
+	SpeedTimePoint []SpeedTimePoint   `json:"speed_time_point"`
+	Outliers       []SpeedTimePoint   `json:"outliers"`
+	Anchors        []SpeedTimePoint   `json:"anchors,omitempty"` // 包络拟合的锚点
+}
+
+func ConvertPaceZones(zones []criticalpower.PaceZone) []PaceZone {
+	finite := func(v float64) *float64 {
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil
+		}
+		return &v
+	}
+	result := make([]PaceZone, len(zones))
+	for i, z := range zones {
+		result[i] = PaceZone{
+			Name:     z.Name,
+			MinSpeed: z.MinSpeed,
+			MaxSpeed: z.MaxSpeed,
+			SlowPace: finite(z.SlowPace),
+			FastPace: finite(z.FastPace),
+		}
+	}
+	return result
+}
+
+type covariance struct {
+	Params           []string           `json:"params"`
+	Matrix           [][]float64        `json:"matrix"`
+	Correlation      [][]float64        `json:"correlation"`
+	StdErr           map[string]float64 `json:"std_err"`
+	PoorlyIdentified []string           `json:"poorly_identified"`
+}
+
+type interval struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+}
+
+type posterior struct {
+	Samples        int                 `json:"samples"`
+	Chains         int                 `json:"chains"`
+	Level          float64             `json:"level"`
+	AcceptanceRate float64             `json:"acceptance_rate"`
+	Means          map[string]float64  `json:"means"`
+	Intervals      map[string]interval `json:"intervals"`
+	RHat           map[string]float64  `json:"rhat"`
+	Sigma          float64             `json:"sigma"` // 相对误差的标准差
+	Priors         map[string]Prior    `json:"priors"`
+	Predictive     []PredictivePoint   `json:"predictive"`
+}
+
+// PredictivePoint 后验预测曲线上的一点，credible 为曲线的可信区间，lower 与 upper 为包含测试误差的预测区间
+type PredictivePoint struct {
+	Time          float64 `json:"time"`
+	Mean          float64 `json:"mean"`
+	CredibleLower float64 `json:"credible_lower"`
+	CredibleUpper float64 `json:"credible_upper"`
+	Lower         float64 `json:"lower"`
+	Upper         float64 `json:"upper"`
+}
+
+// PowerTimeBand 功率-时间曲线在某一时间的预测区间
+type PowerTimeBand struct {
+	Time  float64 `json:"time"`
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+}
+
+// TrainingZone 训练区间，按强度从低到高排列
+type TrainingZone struct {
+	Name string  `json:"name"`
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+}
+
+func ConvertZones(zones []criticalpower.Zone) []TrainingZone {
+	result := make([]TrainingZone, len(zones))
+	for i, z := range zones {
+		result[i] = TrainingZone{Name: z.Name, Min: z.Min, Max: z.Max}
+	}
+	return result
+}
+
+// ZoneTime 运动记录在某个训练区间内的时间与做功
+type ZoneTime struct {
+	TrainingZone
+	Seconds int     `json:"seconds"`
+	Percent float64 `json:"percent"`
+	Work    float64 `json:"work"` // 千焦
+}
+
+// TimeInZoneResponse 运动记录的区间分布
+type TimeInZoneResponse struct {
+	ZoneScheme string             `json:"zone_scheme"`
+	Smoothing  int                `json:"smoothing"` // 划分区间前滚动平均的窗口（秒），0 表示不平滑
+	Params     map[string]float64 `json:"params"`
+	Activity   ActivitySummary    `json:"activity"`
+	Zones      []ZoneTime         `json:"zones"`
+	Warning    string             `json:"warning,omitempty"` // 模型参数由运动记录本身估计时的提示
+}
+
+// rideParamsWarning 未给出模型参数、根据运动记录本身拟合模型时的提示
+const rideParamsWarning = "未给出模型参数，CP 等参数由本次运动记录的平均最大功率曲线拟合；其中大多不是全力的努力，CP 通常偏低，区间边界仅供参考，建议通过 cp、wprime 等查询参数给出测试得到的参数"
+
+func ConvertZoneTimes(zones []criticalpower.ZoneTime) []ZoneTime {
+	result := make([]ZoneTime, len(zones))
+	for i, z := range zones {
+		result[i] = ZoneTime{
+			TrainingZone: TrainingZone{Name: z.Name, Min: z.Min, Max: z.Max},
+			Seconds:      z.Seconds,
+			Percent:      z.Percent,
+			Work:         z.Work,
+		}
+	}
+	return result
+}
+
+// CompareRequest 模型比较请求，Model 字段被忽略
+type CompareRequest struct {
+	CalculateRequest
+	Models []string `json:"models"` // 需要比较的模型，为空时比较全部模型
+}
+
+// ModelTypes 解析需要比较的模型
+func (req *CompareRequest) ModelTypes() ([]criticalpower.ModelType, error) {
+	if len(req.Models) == 0 {
+		return []criticalpower.ModelType{
+			criticalpower.TwoParameter,
+			criticalpower.ThreeParameter,
+			criticalpower.Exponential,
+			criticalpower.OmniDomain,
+		}, nil
+	}
+	modelTypes := make([]criticalpower.ModelType, 0, len(req.Models))
+	for _, name := range req.Models {
+		modelType, err := criticalpower.ParseModelType(name)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(modelTypes, modelType) {
+			modelTypes = append(modelTypes, modelType)
+		}
+	}
+	return modelTypes, nil
+}
+
+// ModelComparison 单个模型的比较结果
+type ModelComparison struct {
+	Model          string             `json:"model"`
+	Error          string             `json:"error,omitempty"`
+	Params         map[string]float64 `json:"params,omitempty"`
+	K              int                `json:"k"`
+	RMSE           float64            `json:"rmse"`
+	AIC            float64            `json:"aic"`
+	AICc           *float64           `json:"aicc"` // 样本量不足时为 null
+	BIC            float64            `json:"bic"`
+	Weight         float64            `json:"weight"`
+	PowerTimeCurve []PowerTimePoint   `json:"power_time_curve,omitempty"`
+}
+
+// CompareResponse 模型比较结果，按信息准则从优到劣排序
+type CompareResponse struct {
+	Results        []ModelComparison `json:"results"`
+	PowerTimePoint []PowerTimePoint  `json:"power_time_point"`
+}
+
+// ModelRequest 需要临界功率模型的请求
+// 提供 params 时直接使用已有的拟合参数（参数名与 /calculate 返回的 params 一致），否则使用 pt 重新拟合
+type ModelRequest struct {
+	CalculateRequest
+	Params map[string]float64 `json:"params"`
+}
+
+// Model 根据请求得到模型
+func (req *ModelRequest) Model() (*criticalpower.CriticalPowerModel, error) {
+	options, err := req.ModelOptions()
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Params) > 0 {
+		model := criticalpower.New(options...)
+		if err := model.SetParams(req.Params); err != nil {
+			return nil, err
+		}
+		return model, nil
+	}
+	return CalculateModel(ConvertPowerTimePointToCP(req.PT), options...)
+}
+
+// WbalRequest W'bal 计算请求
+type WbalRequest struct {
+	ModelRequest
+	Power  []float64 `json:"power"`  // 1 Hz 功率流
+	Method string    `json:"method"` // integral 或 differential
+}
+
+// WbalResponse W'bal 计算结果
+type WbalResponse struct {
+	Method      string             `json:"method"`
+	Params      map[string]float64 `json:"params"`
+	CP          float64            `json:"cp"`
+	Wprime      float64            `json:"wprime"`
+	Balance     []float64          `json:"balance"`
+	Min         float64            `json:"min"`
+	MinTime     int                `json:"min_time"`
+	ExhaustedAt *int               `json:"exhausted_at"` // 未耗尽时为 null
+}
+
+// ThreeMinuteTestRequest 3 分钟全力测试分析请求
+type ThreeMinuteTestRequest struct {
+	Power      []float64        `json:"power"`       // 1 Hz 功率流
+	ZoneScheme string           `json:"zone_scheme"` // 训练区间方案：coggan、seiler 或 polarized
+	Zones      []ZoneDefinition `json:"zones"`       // 自定义训练区间，给出时忽略 zone_scheme
+}
+
+// Normalize 填充默认值
+func (req *ThreeMinuteTestRequest) Normalize() {
+	if req.ZoneScheme == "" {
+		req.ZoneScheme = criticalpower.DefaultZoneScheme
+	}
+}
+
+// ThreeMinuteTestResponse 3 分钟全力测试分析结果
+type ThreeMinuteTestResponse struct {
+	Model         string             `json:"model"`
+	Params        map[string]float64 `json:"params"`
+	CP            float64            `json:"cp"`
+	Wprime        float64            `json:"wprime"`
+	Pmax          float64            `json:"pmax"`
+	EndPower      float64            `json:"end_power"`
+	WorkAboveEP   float64            `json:"work_above_ep"`
+	PeakPower     float64            `json:"peak_power"`
+	PeakTime      int                `json:"peak_time"` // 相对测试开始（秒）
+	Start         int                `json:"start"`     // 测试在功率流中的开始时间（秒）
+	Valid         bool               `json:"valid"`
+	Flags         []string           `json:"flags"`
+	ZoneScheme    string             `json:"zone_scheme"`
+	TrainingZones []TrainingZone     `json:"training_zones"`
+}
+
+// RampTestRequest 递增负荷测试分析请求，给出 pt 时将测试估计的 CP 作为先验信息拟合模型
+type RampTestRequest struct {
+	CalculateRequest
+	Power     []float64 `json:"power"`      // 1 Hz 功率流
+	MAPMethod string    `json:"map_method"` // last_minute 或 best_60s
+	CPRatio   float64   `json:"cp_ratio"`   // CP 与 MAP 的比例，0 表示使用所选方法的默认比例
+}
+
+// RampOptions 返回递增负荷测试的分析选项
+func (req *RampTestRequest) RampOptions() ([]criticalpower.RampOption, error) {
+	method, err := criticalpower.ParseMAPMethod(req.MAPMethod)
+	if err != nil {
+		return nil, err
+	}
+	if req.CPRatio < 0 || req.CPRatio > 1 {
+		return nil, errors.New("cp_ratio 必须在 0 到 1 之间")
+	}
+	return []criticalpower.RampOption{
+		criticalpower.WithMAPMethod(method),
+		criticalpower.WithCPRatio(method, req.CPRatio),
+	}, nil
+}
+
+// RampStep 递增负荷测试的台阶
+type RampStep struct {
+	Start     int     `json:"start"`
+	Duration  int     `json:"duration"`
+	Power     float64 `json:"power"`
+	Completed bool    `json:"completed"`
+}
+
+// RampTestResponse 递增负荷测试分析结果
+type RampTestResponse struct {
+	Steps         []RampStep         `json:"steps"`
+	StepDuration  int                `json:"step_duration"`
+	StepIncrement float64            `json:"step_increment"`
+	LastMinute    float64            `json:"last_minute"`
+	Best60        float64            `json:"best_60s"`
+	MAPMethod     string             `json:"map_method"`
+	MAP           float64            `json:"map"`
+	CPRatio       float64            `json:"cp_ratio"`
+	CP            float64            `json:"cp"`
+	CPPrior       Prior              `json:"cp_prior"` // 贝叶斯拟合使用的 CP 先验
+	Prior         *PowerTimePoint    `json:"prior"`    // 非贝叶斯拟合并入数据的先验点，未并入时为 null
+	Fit           *CalculateResponse `json:"fit"`      // 未给出 pt 时为 null
+}
+
+func ConvertRampSteps(steps []criticalpower.RampStep) []RampStep {
+	result := make([]RampStep, len(steps))
+	for i, s := range steps {
+		result[i] = RampStep{Start: s.Start, Duration: s.Duration, Power: s.Power, Completed: s.Completed}
+	}
+	return result
+}
+
+// IntervalRequest 间歇训练可行性请求，训练与恢复功率可以用瓦或 CP 的比例给出
+type IntervalRequest struct {
+	ModelRequest
+	Reps         int     `json:"reps"`
+	WorkDuration int     `json:"work_duration"` // 秒
+	WorkPower    float64 `json:"work_power"`
+	WorkRatio    float64 `json:"work_ratio"` // work_power 为 0 时使用 work_ratio × CP
+	RestDuration int     `json:"rest_duration"`
+	RestPower    float64 `json:"rest_power"`
+	RestRatio    float64 `json:"rest_ratio"` // rest_power 为 0 时使用 rest_ratio × CP
+	Method       string  `json:"method"`     // W'bal 计算方法：integral 或 differential
+}
+
+// Validate 检查间歇训练总时长的上限，其余参数错误由 SimulateIntervals 检查
+func (req *IntervalRequest) Validate() error {
+	if req.Reps > maxSessionDuration || req.WorkDuration > maxSessionDuration || req.RestDuration > maxSessionDuration ||
+		req.Reps*(req.WorkDuration+req.RestDuration) > maxSessionDuration {
+		return fmt.Errorf("间歇训练总时长不能超过 %d 秒", maxSessionDuration)
+	}
+	return nil
+}
+
+// Session 根据模型的 CP 构造间歇训练
+func (req *IntervalRequest) Session(cp float64) criticalpower.IntervalSession {
+	session := criticalpower.IntervalSession{
+		Reps:         req.Reps,
+		WorkDuration: req.WorkDuration,
+		WorkPower:    req.WorkPower,
+		RestDuration: req.RestDuration,
+		RestPower:    req.RestPower,
+	}
+	if session.WorkPower == 0 {
+		session.WorkPower = req.WorkRatio * cp
+	}
+	if session.RestPower == 0 {
+		session.RestPower = req.RestRatio * cp
+	}
+	return session
+}
+
+// IntervalResponse 间歇训练的模拟结果
+type IntervalResponse struct {
+	Method        string             `json:"method"`
+	Params        map[string]float64 `json:"params"`
+	CP            float64            `json:"cp"`
+	Wprime        float64            `json:"wprime"`
+	WorkPower     float64            `json:"work_power"`
+	RestPower     float64            `json:"rest_power"`
+	Feasible      bool               `json:"feasible"`
+	CompletedReps int                `json:"completed_reps"`
+	ExhaustedAt   *int               `json:"exhausted_at"`  // 未耗尽时为 null
+	ExhaustedRep  *int               `json:"exhausted_rep"` // 未耗尽时为 null
+	MaxWorkPower  float64            `json:"max_work_power"`
+	Balance       []float64          `json:"balance"`
+	MinBalance    float64            `json:"min_balance"`
+}
+
+// WorkoutStep 训练课程中的一段
+type WorkoutStep struct {
+	Duration int      `json:"duration"`  // 秒
+	Power    float64  `json:"power"`     // 起始功率
+	PowerEnd *float64 `json:"power_end"` // 结束功率，未给出时为恒定功率
+	Unit     string   `json:"unit"`      // cp（CP 的比例，默认）或 watts
+}
+
+// WorkoutRequest 训练课程导出请求，使用模型的 CP 换算相对功率
+type WorkoutRequest struct {
+	ModelRequest
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Format      string        `json:"format"` // zwo、erg 或 mrc，默认为 zwo
+	Steps       []WorkoutStep `json:"steps"`
+}
+
+// Workout 返回请求的训练课程
+func (req *WorkoutRequest) Workout() *workout.Workout {
+	w := &workout.Workout{
+		Name:        req.Name,
+		Description: req.Description,
+		Steps:       make([]workout.Step, len(req.Steps)),
+	}
+	for i, s := range req.Steps {
+		w.Steps[i] = workout.Step{
+			Duration: s.Duration,
+			Power:    s.Power,
+			PowerEnd: s.PowerEnd,
+			Unit:     workout.PowerUnit(s.Unit),
+		}
+	}
+	return w
+}
+
+// CoursePoint 路线上的点
+type CoursePoint struct {
+	Distance  float64 `json:"distance"`  // 距起点的水平距离（米）
+	Elevation float64 `json:"elevation"` // 海拔（米）
+}
+
+// CourseRequest 路线用时预测请求，未给出的物理参数使用默认值
+type CourseRequest struct {
+	ModelRequest
+	Points         []CoursePoint `json:"points"`
+	Power          float64       `json:"power"`           // 恒定功率，为 0 时根据模型计算最佳用时
+	Mass           float64       `json:"mass"`            // 骑手与车的总质量（kg）
+	CdA            float64       `json:"cda"`             // 风阻面积（m²）
+	Crr            float64       `json:"crr"`             // 滚动阻力系数
+	DrivetrainLoss *float64      `json:"drivetrain_loss"` // 传动损失比例
+	Temperature    *float64      `json:"temperature"`     // 气温（℃）
+	Wind           float64       `json:"wind"`            // 迎面风速（m/s），顺风为负
+}
+
+// Rider 返回请求的骑手参数
+func (req *CourseRequest) Rider() physics.Rider {
+	rider := physics.DefaultRider()
+	if req.Mass > 0 {
+		rider.Mass = req.Mass
+	}
+	if req.CdA > 0 {
+		rider.CdA = req.CdA
+	}
+	if req.Crr > 0 {
+		rider.Crr = req.Crr
+	}
+	if req.DrivetrainLoss != nil {
+		rider.DrivetrainLoss = *req.DrivetrainLoss
+	}
+	if req.Temperature != nil {
+		rider.Temperature = *req.Temperature
+	}
+	rider.Wind = req.Wind
+	return rider
+}
+
+// Validate 检查路线点数的上限，计算最佳用时需要多次逐段求解速度
+func (req *CourseRequest) Validate() error {
+	if len(req.Points) > maxCoursePoints {
+		return fmt.Errorf("路线点不能超过 %d 个", maxCoursePoints)
+	}
+	return nil
+}
+
+// Course 返回请求的路线
+func (req *CourseRequest) Course() physics.Course {
+	course := make(physics.Course, len(req.Points))
+	for i, p := range req.Points {
+		course[i] = physics.Point{Distance: p.Distance, Elevation: p.Elevation}
+	}
+	return course
+}
+
+// CourseSplit 路段的速度与用时
+type CourseSplit struct {
+	Start    float64 `json:"start"`
+	Distance float64 `json:"distance"`
+	Grade    float64 `json:"grade"`
+	Power    float64 `json:"power"`
+	Speed    float64 `json:"speed"` // m/s
+	Time     float64 `json:"time"`  // 秒
+}
+
+// CourseResponse 路线用时预测结果
+type CourseResponse struct {
+	Params       map[string]float64 `json:"params,omitempty"` // 计算最佳用时所用的模型参数
+	Power        float64            `json:"power"`
+	Time         float64            `json:"time"`
+	Distance     float64            `json:"distance"`
+	Climbing     float64            `json:"climbing"`
+	AverageSpeed float64            `json:"average_speed"`
+	Splits       []CourseSplit      `json:"splits"`
+}
+
+// PacingSegment 配速计划中的赛段，duration 与 distance 只能给出一个
+type PacingSegment struct {
+	Duration float64 `json:"duration"` // 秒
+	Distance float64 `json:"distance"` // 米
+	Effort   float64 `json:"effort"`   // 相对强度，默认为 1
+}
+
+// PacingRequest 配速计划请求，没有给出 segments 时按 duration 或 distance 计算恒定功率
+type PacingRequest struct {
+	ModelRequest
+	Duration       float64         `json:"duration"`        // 目标时长（秒）
+	Distance       float64         `json:"distance"`        // 目标距离（米）
+	Segments       []PacingSegment `json:"segments"`        // 赛段划分
+	ReferencePower float64         `json:"reference_power"` // 按距离计划时的参考功率（瓦）
+	ReferenceSpeed float64         `json:"reference_speed"` // 参考功率下的速度（米/秒）
 }
 
-type TrainingZones struct {
-	RecoveryZone      zone `json:"recovery_zone"`
-	EnduranceZone     zone `json:"endurance_zone"`
-	TempoZone         zone `json:"tempo_zone"`
-	ThresholdZone     zone `json:"threshold_zone"`
-	VO2MaxZone        zone `json:"vo2max_zone"`
-	AnaerobicZone     zone `json:"anaerobic_zone"`
-	NeuromuscularZone zone `json:"neuromuscular_zone"`
+// PacingSegments 返回请求的赛段
+func (req *PacingRequest) PacingSegments() []criticalpower.PacingSegment {
+	if len(req.Segments) == 0 {
+		return []criticalpower.PacingSegment{{Duration: req.Duration, Distance: req.Distance}}
+	}
+	segments := make([]criticalpower.PacingSegment, len(req.Segments))
+	for i, s := range req.Segments {
+		segments[i] = criticalpower.PacingSegment{Duration: s.Duration, Distance: s.Distance, Effort: s.Effort}
+	}
+	return segments
+}
+
+// Validate 检查赛段数量与计划总时长的上限
+//
+// 按距离的赛段用时取决于功率，按二分查找可能尝试的最低功率（强度最高的赛段为 CP 的一半）换算，
+// 得到计划时长的上界。其余参数错误由 PlanPacing 检查。
+func (req *PacingRequest) Validate(cp float64) error {
+	segments := req.PacingSegments()
+	if len(segments) > maxPacingSegments {
+		return fmt.Errorf("赛段不能超过 %d 个", maxPacingSegments)
+	}
+	speed := req.SpeedReference()
+	maxEffort := 0.0
+	for _, s := range segments {
+		maxEffort = max(maxEffort, pacingEffort(s.Effort))
+	}
+	total := 0.0
+	for _, s := range segments {
+		duration := s.Duration
+		if s.Distance > 0 && speed != nil && speed.Power > 0 && speed.Speed > 0 && maxEffort > 0 {
+			power := cp * pacingEffort(s.Effort) / maxEffort / 2
+			duration = s.Distance / (speed.Speed * math.Cbrt(power/speed.Power))
+		}
+		if duration > 0 {
+			total += duration
+		}
+	}
+	if !(total <= maxSessionDuration) {
+		return fmt.Errorf("配速计划总时长不能超过 %d 秒", maxSessionDuration)
+	}
+	return nil
+}
+
+// pacingEffort 返回赛段的相对强度，0 视为 1
+func pacingEffort(effort float64) float64 {
+	if effort == 0 {
+		return 1
+	}
+	return effort
+}
+
+// SpeedReference 返回按距离计划时的速度模型，没有给出参考功率与速度时为 nil
+func (req *PacingRequest) SpeedReference() *criticalpower.SpeedReference {
+	if req.ReferencePower == 0 && req.ReferenceSpeed == 0 {
+		return nil
+	}
+	return &criticalpower.SpeedReference{Power: req.ReferencePower, Speed: req.ReferenceSpeed}
+}
+
+// PlannedSegment 赛段的目标功率
+type PlannedSegment struct {
+	Start    float64 `json:"start"`
+	Duration float64 `json:"duration"`
+	Distance float64 `json:"distance"`
+	Power    float64 `json:"power"`
+}
+
+// PacingResponse 恰好在终点耗尽 W' 的配速计划
+type PacingResponse struct {
+	Params       map[string]float64 `json:"params"`
+	CP           float64            `json:"cp"`
+	Wprime       float64            `json:"wprime"`
+	Duration     float64            `json:"duration"`
+	Distance     float64            `json:"distance"`
+	AveragePower float64            `json:"average_power"`
+	Segments     []PlannedSegment   `json:"segments"`
+	Balance      []float64          `json:"balance"` // 每秒的 W'bal
+	MinBalance   float64            `json:"min_balance"`
+	MinTime      float64            `json:"min_time"`
+}
+
+// LoadRequest 单次骑行训练负荷计算请求
+type LoadRequest struct {
+	ModelRequest
+	Power []float64 `json:"power"` // 1 Hz 功率流
+}
+
+// LoadResponse 单次骑行的训练负荷
+type LoadResponse struct {
+	Params           map[string]float64 `json:"params"`
+	CP               float64            `json:"cp"`
+	Duration         int                `json:"duration"`
+	Work             float64            `json:"work"` // 千焦
+	AveragePower     float64            `json:"average_power"`
+	NormalizedPower  float64            `json:"normalized_power"`
+	IntensityFactor  float64            `json:"intensity_factor"`
+	TSS              float64            `json:"tss"`
+	VariabilityIndex float64            `json:"variability_index"`
+}
+
+// DailyLoad 某一天的训练负荷
+type DailyLoad struct {
+	Date string  `json:"date"` // YYYY-MM-DD 或 RFC 3339
+	TSS  float64 `json:"tss"`
+}
+
+// PMCRequest 表现管理图计算请求
+type PMCRequest struct {
+	History    []DailyLoad `json:"history"`     // 已完成的训练
+	Planned    []DailyLoad `json:"planned"`     // 计划的训练，用于预测未来的状态
+	CTLDays    float64     `json:"ctl_days"`    // CTL 时间常数，默认 42 天
+	ATLDays    float64     `json:"atl_days"`    // ATL 时间常数，默认 7 天
+	InitialCTL float64     `json:"initial_ctl"` // 第一天之前的 CTL
+	InitialATL float64     `json:"initial_atl"` // 第一天之前的 ATL
+}
+
+// Options 根据请求构造表现管理图选项
+func (req *PMCRequest) Options() []pmc.Option {
+	return []pmc.Option{
+		pmc.WithCTLDays(req.CTLDays),
+		pmc.WithATLDays(req.ATLDays),
+		pmc.WithInitial(req.InitialCTL, req.InitialATL),
+	}
+}
+
+// ConvertDailyLoads 解析训练负荷中的日期
+func ConvertDailyLoads(loads []DailyLoad) ([]pmc.Load, error) {
+	result := make([]pmc.Load, len(loads))
+	for i, load := range loads {
+		date, err := time.Parse(time.DateOnly, load.Date)
+		if err != nil {
+			if date, err = time.Parse(time.RFC3339, load.Date); err != nil {
+				return nil, errors.New("无效的日期: " + load.Date)
+			}
+		}
+		result[i] = pmc.Load{Date: date, TSS: load.TSS}
+	}
+	return result, nil
+}
+
+// PMCDay 某一天的 CTL、ATL 与 TSB
+type PMCDay struct {
+	Date    string  `json:"date"`
+	TSS     float64 `json:"tss"`
+	CTL     float64 `json:"ctl"`
+	ATL     float64 `json:"atl"`
+	TSB     float64 `json:"tsb"`
+	Planned bool    `json:"planned"` // 为 true 时是根据计划预测的值
+}
+
+// PMCResponse 表现管理图的时间序列
+type PMCResponse struct {
+	CTLDays float64  `json:"ctl_days"`
+	ATLDays float64  `json:"atl_days"`
+	Days    []PMCDay `json:"days"`
+}
+
+func ConvertPMCDays(days []pmc.Day) []PMCDay {
+	result := make([]PMCDay, len(days))
+	for i, d := range days {
+		result[i] = PMCDay{
+			Date:    d.Date.Format(time.DateOnly),
+			TSS:     d.TSS,
+			CTL:     d.CTL,
+			ATL:     d.ATL,
+			TSB:     d.TSB,
+			Planned: d.Planned,
+		}
+	}
+	return result
+}
+
+// MMPRequest 平均最大功率曲线提取请求
+type MMPRequest struct {
+	Power     []float64 `json:"power"`     // 1 Hz 功率流
+	Durations []int     `json:"durations"` // 需要提取的时长（秒），为空时使用默认时长
+	All       bool      `json:"all"`       // 是否返回每一秒的平均最大功率，为 true 时忽略 durations
+	Censored  bool      `json:"censored"`  // 训练骑行的数据不是最大努力，返回的点标记为删失
+}
+
+// MMPResponse 平均最大功率曲线，pt 可以直接作为 /calculate 的输入
+type MMPResponse struct {
+	PT []PowerTimePoint `json:"pt"`
+}
+
+// ActivitySummary 导入的运动记录的基本信息
+type ActivitySummary struct {
+	Start        time.Time `json:"start"`
+	Duration     int       `json:"duration"` // 重采样后的时长（秒）
+	AveragePower float64   `json:"average_power"`
+	MaxPower     float64   `json:"max_power"`
+}
+
+// ImportResponse 导入运动记录并拟合模型的结果
+type ImportResponse struct {
+	CalculateResponse
+	Activity ActivitySummary `json:"activity"`
+}
+
+// CSVRowError CSV 中无法导入的行
+type CSVRowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+// CSVImportResponse 导入 CSV 并拟合模型的结果，Errors 为被跳过的行
+type CSVImportResponse struct {
+	CalculateResponse
+	Errors []CSVRowError `json:"errors"`
+}
+
+// CSVImportError CSV 中没有可用的数据或拟合失败时的响应
+type CSVImportError struct {
+	Error  string        `json:"error"`
+	Errors []CSVRowError `json:"errors"`
+}
+
+func ConvertRowErrors(rowErrors []csvimport.RowError) []CSVRowError {
+	result := make([]CSVRowError, len(rowErrors))
+	for i, e := range rowErrors {
+		result[i] = CSVRowError{Row: e.Row, Message: e.Message}
+	}
+	return result
 }
@@ -0,0 +1,233 @@
+// Package physics 骑行的物理模型，根据功率计算路线上的速度与用时
+//
+// 稳态下踏板功率扣除传动损失后用于克服重力、滚动阻力与空气阻力：
+//
+//	P × (1 - loss) = v × (m g (sin θ + Crr cos θ) + ½ ρ CdA (v + w)²)
+//
+// 其中 ρ 为空气密度，随海拔与气温变化，w 为迎面风速。忽略加减速带来的动能变化。
+package physics
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/Equationzhao/power/criticalpower"
+)
+
+// 物理常数
+const (
+	Gravity             = 9.80665  // 重力加速度（m/s²）
+	seaLevelPressure    = 101325.0 // 海平面标准气压（Pa）
+	specificGasConstant = 287.05   // 干空气的比气体常数（J/(kg·K)）
+	temperatureLapse    = 0.0065   // 对流层气温递减率（K/m）
+	standardTemperature = 288.15   // 海平面标准气温（K）
+)
+
+// 默认参数，适用于公路车上把位骑行
+const (
+	DefaultMass           = 80.0  // 骑手与车的总质量（kg）
+	DefaultCdA            = 0.32  // 风阻面积（m²）
+	DefaultCrr            = 0.004 // 滚动阻力系数
+	DefaultDrivetrainLoss = 0.025 // 传动损失比例
+	DefaultTemperature    = 20.0  // 气温（℃）
+)
+
+// maxSpeed 求解速度时的上限（m/s）
+const maxSpeed = 100.0
+
+// minSpeed 速度的下限（m/s），功率不足以前进时按此速度计算用时
+const minSpeed = 0.5
+
+// Rider 骑手与环境参数
+type Rider struct {
+	Mass           float64 // 骑手与车的总质量（kg）
+	CdA            float64 // 风阻面积（m²）
+	Crr            float64 // 滚动阻力系数
+	DrivetrainLoss float64 // 传动损失比例，例如 0.025
+	Temperature    float64 // 气温（℃）
+	Wind           float64 // 迎面风速（m/s），顺风为负
+}
+
+// DefaultRider 返回默认参数的骑手
+func DefaultRider() Rider {
+	return Rider{
+		Mass:           DefaultMass,
+		CdA:            DefaultCdA,
+		Crr:            DefaultCrr,
+		DrivetrainLoss: DefaultDrivetrainLoss,
+		Temperature:    DefaultTemperature,
+	}
+}
+
+// Validate 检查参数是否有效
+func (r Rider) Validate() error {
+	if r.Mass <= 0 || r.CdA <= 0 {
+		return errors.New("质量与风阻面积必须大于0")
+	}
+	if r.Crr < 0 || r.DrivetrainLoss < 0 || r.DrivetrainLoss >= 1 {
+		return errors.New("滚动阻力系数不能为负，传动损失必须在 0 到 1 之间")
+	}
+	if r.Temperature <= -273.15 {
+		return errors.New("无效的气温")
+	}
+	return nil
+}
+
+// AirDensity 根据海拔（米）与气温（℃）计算空气密度（kg/m³），气压按国际标准大气随海拔变化
+func AirDensity(altitude, temperature float64) float64 {
+	pressure := seaLevelPressure * math.Pow(1-temperatureLapse*altitude/standardTemperature, 5.25588)
+	return pressure / (specificGasConstant * (temperature + 273.15))
+}
+
+// Speed 求解给定功率、坡度（高度差/水平距离）与空气密度下的稳态速度（m/s）
+func (r Rider) Speed(power, grade, density float64) float64 {
+	theta := math.Atan(grade)
+	resistance := r.Mass * Gravity * (math.Sin(theta) + r.Crr*math.Cos(theta))
+	wheelPower := max(power, 0) * (1 - r.DrivetrainLoss)
+	surplus := func(v float64) float64 {
+		air := v + r.Wind
+		return v*(resistance+0.5*density*r.CdA*air*math.Abs(air)) - wheelPower
+	}
+
+	// surplus(0) <= 0，且随速度增大最终为正，二分查找最大的根
+	low, high := 0.0, maxSpeed
+	if surplus(high) <= 0 {
+		return maxSpeed
+	}
+	for range 100 {
+		mid := (low + high) / 2
+		if surplus(mid) <= 0 {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return low
+}
+
+// Power 求解在给定坡度与空气密度下保持速度 v 所需的功率
+func (r Rider) Power(v, grade, density float64) float64 {
+	theta := math.Atan(grade)
+	air := v + r.Wind
+	resistance := r.Mass*Gravity*(math.Sin(theta)+r.Crr*math.Cos(theta)) + 0.5*density*r.CdA*air*math.Abs(air)
+	return max(v*resistance/(1-r.DrivetrainLoss), 0)
+}
+
+// Point 路线上的点
+type Point struct {
+	Distance  float64 // 距起点的水平距离（米）
+	Elevation float64 // 海拔（米）
+}
+
+// Course 路线，由按距离排列的点组成，相邻两点之间视为坡度恒定的路段
+type Course []Point
+
+// Validate 检查路线是否有效
+func (c Course) Validate() error {
+	if len(c) < 2 {
+		return errors.New("路线至少需要两个点")
+	}
+	for i := 1; i < len(c); i++ {
+		if c[i].Distance <= c[i-1].Distance {
+			return fmt.Errorf("第%d个点的距离必须大于前一个点", i+1)
+		}
+	}
+	return nil
+}
+
+// Distance 返回路线的总距离（米）
+func (c Course) Distance() float64 {
+	return c[len(c)-1].Distance - c[0].Distance
+}
+
+// Climbing 返回路线的累计爬升（米）
+func (c Course) Climbing() float64 {
+	total := 0.0
+	for i := 1; i < len(c); i++ {
+		total += max(c[i].Elevation-c[i-1].Elevation, 0)
+	}
+	return total
+}
+
+// Split 路段的计算结果
+type Split struct {
+	Start    float64 // 起点距离（米）
+	Distance float64 // 路段长度（米）
+	Grade    float64 // 坡度
+	Power    float64 // 功率（瓦）
+	Speed    float64 // 速度（m/s）
+	Time     float64 // 用时（秒）
+}
+
+// Result 骑完整条路线的结果
+type Result struct {
+	Splits       []Split
+	Time         float64 // 总用时（秒）
+	Distance     float64 // 总距离（米）
+	AveragePower float64 // 按时间加权的平均功率
+	AverageSpeed float64 // 平均速度（m/s），按水平距离计算
+}
+
+// Ride 计算以 power(i) 的功率骑第 i 个路段时各路段的速度与用时
+func (r Rider) Ride(course Course, power func(i int) float64) (*Result, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	if err := course.Validate(); err != nil {
+		return nil, err
+	}
+
+	result := &Result{Splits: make([]Split, len(course)-1)}
+	work := 0.0
+	for i := range result.Splits {
+		from, to := course[i], course[i+1]
+		distance := to.Distance - from.Distance
+		grade := (to.Elevation - from.Elevation) / distance
+		density := AirDensity((from.Elevation+to.Elevation)/2, r.Temperature)
+		p := power(i)
+		speed := max(r.Speed(p, grade, density), minSpeed)
+		// 坡道上的实际路程略长于水平距离
+		t := distance * math.Sqrt(1+grade*grade) / speed
+
+		result.Splits[i] = Split{Start: from.Distance, Distance: distance, Grade: grade, Power: p, Speed: speed, Time: t}
+		result.Time += t
+		work += p * t
+	}
+	result.Distance = course.Distance()
+	result.AveragePower = work / result.Time
+	result.AverageSpeed = result.Distance / result.Time
+	return result, nil
+}
+
+// RideConstant 以恒定功率骑完整条路线
+func (r Rider) RideConstant(course Course, power float64) (*Result, error) {
+	return r.Ride(course, func(int) float64 { return power })
+}
+
+// bestTimeIterations 二分查找最佳恒定功率的次数
+const bestTimeIterations = 60
+
+// BestTime 根据功率-时间模型计算路线的最佳用时
+//
+// 以恒定功率 P 骑完路线的用时 T(P) 随功率增大而减小，而模型能维持 T 的最大功率
+// 随 T 增大而减小，二分查找满足 P = PredictPower(T(P)) 的功率，即恰好能维持到终点的最大恒定功率。
+func (r Rider) BestTime(course Course, model criticalpower.Model) (*Result, error) {
+	low, high := 0.0, model.PredictPower(1)
+	if high <= 0 || math.IsNaN(high) || math.IsInf(high, 0) {
+		return nil, errors.New("模型的最大功率无效")
+	}
+	for range bestTimeIterations {
+		mid := (low + high) / 2
+		result, err := r.RideConstant(course, mid)
+		if err != nil {
+			return nil, err
+		}
+		if mid < model.PredictPower(result.Time) {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return r.RideConstant(course, low)
+}
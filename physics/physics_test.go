@@ -0,0 +1,70 @@
+package physics_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Equationzhao/power/criticalpower"
+	"github.com/Equationzhao/power/physics"
+)
+
+func TestAirDensity(t *testing.T) {
+	// 国际标准大气：海平面 15℃ 时约 1.225 kg/m³
+	if rho := physics.AirDensity(0, 15); math.Abs(rho-1.225) > 1e-3 {
+		t.Errorf("海平面空气密度 = %.4f", rho)
+	}
+	if physics.AirDensity(2000, 15) >= physics.AirDensity(0, 15) {
+		t.Error("海拔升高后空气密度应降低")
+	}
+}
+
+func TestSpeed(t *testing.T) {
+	rider := physics.DefaultRider()
+	rho := physics.AirDensity(0, 20)
+
+	// 求解的速度代回后功率一致
+	for _, grade := range []float64{-0.05, 0, 0.08} {
+		v := rider.Speed(250, grade, rho)
+		if p := rider.Power(v, grade, rho); math.Abs(p-250) > 1e-6 {
+			t.Errorf("坡度 %.2f: 速度 %.2f m/s 所需功率 %.3f, 期望 250", grade, v, p)
+		}
+	}
+	flat := rider.Speed(250, 0, rho)
+	if flat*3.6 < 35 || flat*3.6 > 42 {
+		t.Errorf("平路 250 W 速度 = %.1f km/h", flat*3.6)
+	}
+	// 下坡不踩踏也有速度
+	if v := rider.Speed(0, -0.06, rho); v <= 0 {
+		t.Errorf("下坡滑行速度 = %.2f", v)
+	}
+}
+
+func TestBestTime(t *testing.T) {
+	model := criticalpower.New(criticalpower.WithModel(criticalpower.ThreeParameter))
+	if err := model.SetParams(map[string]float64{"cp": 250, "wprime": 20000, "tau": 30}); err != nil {
+		t.Fatal(err)
+	}
+	// 5 公里 7% 的爬坡
+	course := physics.Course{{Distance: 0, Elevation: 500}, {Distance: 5000, Elevation: 850}}
+
+	rider := physics.DefaultRider()
+	result, err := rider.BestTime(course, model)
+	if err != nil {
+		t.Fatalf("计算失败: %v", err)
+	}
+	t.Logf("用时 %.0f 秒, 功率 %.1f W, 速度 %.1f km/h", result.Time, result.AveragePower, result.AverageSpeed*3.6)
+	if math.Abs(result.AveragePower-model.PredictPower(result.Time)) > 0.01 {
+		t.Errorf("功率 %.2f 与模型在 %.0f 秒的功率 %.2f 不一致", result.AveragePower, result.Time, model.PredictPower(result.Time))
+	}
+
+	// 更轻的骑手更快
+	rider.Mass -= 5
+	lighter, _ := rider.BestTime(course, model)
+	if lighter.Time >= result.Time {
+		t.Errorf("减重后用时 %.0f 秒, 应少于 %.0f 秒", lighter.Time, result.Time)
+	}
+
+	if _, err := rider.RideConstant(physics.Course{{Distance: 0}}, 250); err == nil {
+		t.Error("路线只有一个点时应返回错误")
+	}
+}
@@ -0,0 +1,176 @@
+// Package pmc 表现管理图（Performance Management Chart）
+//
+// 根据每日训练负荷（TSS）计算长期训练负荷 CTL（体能）、短期训练负荷 ATL（疲劳）
+// 以及训练压力平衡 TSB（状态），并可以根据计划的训练负荷预测未来的状态。
+package pmc
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 默认时间常数（天）
+const (
+	DefaultCTLDays = 42.0
+	DefaultATLDays = 7.0
+)
+
+// maxDays 计算的最长天数，防止日期跨度过大
+const maxDays = 366 * 20
+
+// Load 某一天的训练负荷，同一天的多次训练会被累加
+type Load struct {
+	Date time.Time
+	TSS  float64
+}
+
+// Day 某一天的计算结果
+type Day struct {
+	Date    time.Time // 当天 0 点（UTC）
+	TSS     float64   // 当天的训练负荷
+	CTL     float64   // 长期训练负荷
+	ATL     float64   // 短期训练负荷
+	TSB     float64   // 训练压力平衡，等于前一天的 CTL - ATL
+	Planned bool      // 是否为计划的训练负荷（预测值）
+}
+
+// Chart 表现管理图的参数
+type Chart struct {
+	ctlDays    float64
+	atlDays    float64
+	initialCTL float64
+	initialATL float64
+}
+
+// Option 表现管理图选项
+type Option func(*Chart)
+
+// WithCTLDays 设置 CTL 的时间常数（天）
+func WithCTLDays(days float64) Option {
+	return func(c *Chart) {
+		if days <= 0 {
+			days = DefaultCTLDays
+		}
+		c.ctlDays = days
+	}
+}
+
+// WithATLDays 设置 ATL 的时间常数（天）
+func WithATLDays(days float64) Option {
+	return func(c *Chart) {
+		if days <= 0 {
+			days = DefaultATLDays
+		}
+		c.atlDays = days
+	}
+}
+
+// WithInitial 设置第一天之前的 CTL 与 ATL，默认为 0
+func WithInitial(ctl, atl float64) Option {
+	return func(c *Chart) {
+		c.initialCTL = max(ctl, 0)
+		c.initialATL = max(atl, 0)
+	}
+}
+
+// New 创建表现管理图，可以传入选项
+func New(options ...Option) *Chart {
+	c := &Chart{
+		ctlDays: DefaultCTLDays,
+		atlDays: DefaultATLDays,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// CTLDays 返回 CTL 的时间常数（天）
+func (c *Chart) CTLDays() float64 {
+	return c.ctlDays
+}
+
+// ATLDays 返回 ATL 的时间常数（天）
+func (c *Chart) ATLDays() float64 {
+	return c.atlDays
+}
+
+// Compute 计算从第一条训练记录到最后一条计划之间每一天的 CTL、ATL 与 TSB
+//
+// 没有训练的日期负荷为 0。planned 中的日期必须晚于 history 中的最后一天，
+// 对应的结果标记为 Planned。采用与 TrainingPeaks 相同的递推：
+// CTL(t) = CTL(t-1) + (TSS(t) - CTL(t-1)) / 时间常数，ATL 同理。
+func (c *Chart) Compute(history, planned []Load) ([]Day, error) {
+	if len(history) == 0 && len(planned) == 0 {
+		return nil, errors.New("训练负荷为空")
+	}
+
+	daily := make(map[time.Time]float64)
+	var first, lastHistory, last time.Time
+	add := func(load Load) error {
+		if load.TSS < 0 {
+			return fmt.Errorf("训练负荷不能为负: %s", load.Date.Format(time.DateOnly))
+		}
+		date := truncateDay(load.Date)
+		daily[date] += load.TSS
+		if first.IsZero() || date.Before(first) {
+			first = date
+		}
+		if date.After(last) {
+			last = date
+		}
+		return nil
+	}
+	for _, load := range history {
+		if err := add(load); err != nil {
+			return nil, err
+		}
+		if date := truncateDay(load.Date); date.After(lastHistory) {
+			lastHistory = date
+		}
+	}
+	for _, load := range planned {
+		if !lastHistory.IsZero() && !truncateDay(load.Date).After(lastHistory) {
+			return nil, fmt.Errorf("计划日期必须晚于最后一次训练: %s", load.Date.Format(time.DateOnly))
+		}
+		if err := add(load); err != nil {
+			return nil, err
+		}
+	}
+
+	n := int(last.Sub(first).Hours()/24) + 1
+	if n > maxDays {
+		return nil, fmt.Errorf("日期跨度过大: %d 天", n)
+	}
+
+	days := make([]Day, n)
+	ctl, atl := c.initialCTL, c.initialATL
+	for i := range days {
+		date := first.AddDate(0, 0, i)
+		tss := daily[date]
+		tsb := ctl - atl
+		ctl += (tss - ctl) / c.ctlDays
+		atl += (tss - atl) / c.atlDays
+		days[i] = Day{
+			Date:    date,
+			TSS:     tss,
+			CTL:     ctl,
+			ATL:     atl,
+			TSB:     tsb,
+			Planned: date.After(lastHistory),
+		}
+	}
+	return days, nil
+}
+
+// Compute 使用给定选项计算表现管理图
+func Compute(history, planned []Load, options ...Option) ([]Day, error) {
+	return New(options...).Compute(history, planned)
+}
+
+// truncateDay 返回日期所在当天 0 点（UTC），时区以日期本身为准
+func truncateDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
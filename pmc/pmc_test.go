@@ -0,0 +1,67 @@
+package pmc_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Equationzhao/power/pmc"
+)
+
+func date(day int) time.Time {
+	return time.Date(2026, 1, day, 8, 0, 0, 0, time.UTC)
+}
+
+func TestCompute(t *testing.T) {
+	history := []pmc.Load{
+		{Date: date(1), TSS: 100},
+		{Date: date(1), TSS: 50}, // 同一天第二次训练
+		{Date: date(3), TSS: 70},
+	}
+	planned := []pmc.Load{{Date: date(5), TSS: 200}}
+
+	days, err := pmc.Compute(history, planned, pmc.WithCTLDays(10), pmc.WithATLDays(2))
+	if err != nil {
+		t.Fatalf("计算失败: %v", err)
+	}
+	if len(days) != 5 {
+		t.Fatalf("天数 = %d, 期望 5", len(days))
+	}
+
+	// 手工递推
+	ctl, atl := 0.0, 0.0
+	for i, tss := range []float64{150, 0, 70, 0, 200} {
+		tsb := ctl - atl
+		ctl += (tss - ctl) / 10
+		atl += (tss - atl) / 2
+		d := days[i]
+		if d.TSS != tss || math.Abs(d.CTL-ctl) > 1e-9 || math.Abs(d.ATL-atl) > 1e-9 || math.Abs(d.TSB-tsb) > 1e-9 {
+			t.Errorf("第%d天 = %+v, 期望 CTL %.3f ATL %.3f TSB %.3f", i+1, d, ctl, atl, tsb)
+		}
+		if want := i >= 3; d.Planned != want {
+			t.Errorf("第%d天 Planned = %v, 期望 %v", i+1, d.Planned, want)
+		}
+	}
+
+	if _, err := pmc.Compute(history, []pmc.Load{{Date: date(2), TSS: 50}}); err == nil {
+		t.Error("计划日期早于最后一次训练时应返回错误")
+	}
+	if _, err := pmc.Compute([]pmc.Load{{Date: date(1), TSS: -1}}, nil); err == nil {
+		t.Error("负的训练负荷应返回错误")
+	}
+}
+
+// TestInitial 没有训练时 CTL 与 ATL 从初始值衰减
+func TestInitial(t *testing.T) {
+	days, err := pmc.Compute([]pmc.Load{{Date: date(1)}, {Date: date(10)}}, nil, pmc.WithInitial(60, 80))
+	if err != nil {
+		t.Fatalf("计算失败: %v", err)
+	}
+	if days[0].TSB != -20 {
+		t.Errorf("第一天 TSB = %f, 期望 -20", days[0].TSB)
+	}
+	last := days[len(days)-1]
+	if last.CTL >= 60 || last.ATL >= last.CTL {
+		t.Errorf("休息后 CTL = %.1f, ATL = %.1f", last.CTL, last.ATL)
+	}
+}
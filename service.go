@@ -1,15 +1,110 @@
 package main
 
-import "github.com/Equationzhao/power/criticalpower"
+import (
+	"slices"
 
-func CalculateModel(data []criticalpower.PowerTimePoint, runtimes int, outlierDetect bool) (*criticalpower.CriticalPowerModel, error) {
-	option := []criticalpower.ModelOption{criticalpower.WithRunTimes(runtimes)}
-	if outlierDetect {
-		option = append(option, criticalpower.WithOutlierDetect())
-	}
-	model := criticalpower.New(option...)
+	"github.com/Equationzhao/power/activity"
+	"github.com/Equationzhao/power/criticalpower"
+)
+
+const (
+	curveMaxTimeDefault = 7200.0  // 功率-时间曲线默认的最长时间（秒）
+	curveMaxTimeOmPD    = 21600.0 // OmPD 模型功率-时间曲线的最长时间（秒）
+)
+
+func CalculateModel(data []criticalpower.PowerTimePoint, options ...criticalpower.ModelOption) (*criticalpower.CriticalPowerModel, error) {
+	model := criticalpower.New(options...)
 	if err := model.Fit(data); err != nil {
 		return nil, err
 	}
 	return model, nil
 }
+
+// curveTimes 生成绘制功率-时间曲线的时间点，包括数据点本身的时间
+// OmPD 模型能够描述超过 CP 维持时间后的功率衰减，因此曲线延长到 curveMaxTimeOmPD
+func curveTimes(modelType criticalpower.ModelType, pt []PowerTimePoint) []float64 {
+	curveMaxTime := curveMaxTimeDefault
+	if modelType == criticalpower.OmniDomain {
+		curveMaxTime = curveMaxTimeOmPD
+	}
+	timeMap := make(map[float64]struct{})
+	for t := 1.0; t <= curveMaxTime; t *= 1.15 {
+		t = float64(int(t*10)) / 10
+		timeMap[t] = struct{}{}
+	}
+	// 插入固定的几个重要时间点
+	// 1s - 60s
+	// 60s 之后每隔 5s 一个点
+	// 2m 之后每隔 10s 一个点
+	// 5m 之后每隔 30s 一个点
+	// 10m 之后每隔 1min 一个点
+	// 1h 之后每隔 5min 一个点
+	// 2h 之后每隔 10min 一个点
+	for i := 1; i <= 60; i++ {
+		timeMap[float64(i)] = struct{}{}
+	}
+	for i := 60; i <= 120; i += 5 {
+		timeMap[float64(i)] = struct{}{}
+	}
+	for i := 120; i <= 300; i += 10 {
+		timeMap[float64(i)] = struct{}{}
+	}
+	for i := 300; i <= 600; i += 30 {
+		timeMap[float64(i)] = struct{}{}
+	}
+	for i := 600; i <= 3600; i += 60 {
+		timeMap[float64(i)] = struct{}{}
+	}
+	for i := 3600; i <= 7200; i += 300 {
+		timeMap[float64(i)] = struct{}{}
+	}
+	for i := 7200; i <= int(curveMaxTime); i += 600 {
+		timeMap[float64(i)] = struct{}{}
+	}
+	for _, t := range pt {
+		timeMap[t.Time] = struct{}{}
+	}
+	var times []float64
+	for t := range timeMap {
+		times = append(times, t)
+	}
+	slices.Sort(times)
+	return times
+}
+
+// predictCurve 使用模型预测 times 中每个时间点的最大功率
+func predictCurve(model criticalpower.Model, times []float64) []PowerTimePoint {
+	curve := make([]PowerTimePoint, len(times))
+	for i, t := range times {
+		curve[i] = PowerTimePoint{
+			Time:  t,
+			Power: model.PredictPower(t),
+		}
+	}
+	return curve
+}
+
+// MeanMaximalPoints 提取数据流的平均最大功率曲线，去掉功率为 0 的时长
+func MeanMaximalPoints(stream *activity.Stream) []criticalpower.PowerTimePoint {
+	points := criticalpower.MeanMaximalPoints(stream.Power, nil)
+	return slices.DeleteFunc(points, func(p criticalpower.PowerTimePoint) bool {
+		return p.Power <= 0
+	})
+}
+
+// summarizeStream 汇总数据流的基本信息
+func summarizeStream(stream *activity.Stream) ActivitySummary {
+	summary := ActivitySummary{
+		Start:    stream.Start,
+		Duration: stream.Len(),
+	}
+	var sum float64
+	for _, p := range stream.Power {
+		sum += p
+		summary.MaxPower = max(summary.MaxPower, p)
+	}
+	if stream.Len() > 0 {
+		summary.AveragePower = sum / float64(stream.Len())
+	}
+	return summary
+}
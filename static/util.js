@@ -56,6 +56,44 @@ function createPointRow() {
 	return row;
 }
 
+// 训练区间的显示名称、样式与说明，未列出的区间直接显示名称
+const zoneStyles = {
+	recovery: { label: "恢复区间", className: "recovery-text", info: "recovery" },
+	endurance: { label: "耐力区间", className: "endurance-text", info: "endurance" },
+	tempo: { label: "节奏区间", className: "tempo-text", info: "tempo" },
+	threshold: { label: "阈值区间", className: "threshold-text", info: "threshold" },
+	vo2max: { label: "VO₂Max区间", className: "vo2max-text", info: "vo2max_zone" },
+	anaerobic: { label: "无氧区间", className: "anaerobic-text", info: "anaerobic" },
+	neuromuscular: { label: "神经肌肉区间", className: "neuromuscular-text", info: "neuromuscular" },
+	zone1: { label: "低强度区间 (Z1)", className: "endurance-text" },
+	zone2: { label: "中等强度区间 (Z2)", className: "threshold-text" },
+	zone3: { label: "高强度区间 (Z3)", className: "anaerobic-text" },
+	low: { label: "低强度区间", className: "recovery-text" },
+	moderate: { label: "中等强度区间", className: "endurance-text" },
+	heavy: { label: "大强度区间", className: "threshold-text" },
+	severe: { label: "严重强度区间", className: "anaerobic-text" },
+	extreme: { label: "极限强度区间", className: "neuromuscular-text" },
+};
+
+// 转义 HTML 特殊字符，用于插入用户提供的文本
+function escapeHTML(text) {
+	return String(text).replace(/[&<>"']/g, (c) => ({ "&": "&amp;", "<": "&lt;", ">": "&gt;", '"': "&quot;", "'": "&#39;" })[c]);
+}
+
+// 生成训练区间表格的一行，自定义区间的名称需要转义
+function zoneRow(zone) {
+	const style = Object.hasOwn(zoneStyles, zone.name) ? zoneStyles[zone.name] : { label: escapeHTML(zone.name), className: "" };
+	const label = style.info
+		? `<span class="zone-text ${style.className} info-trigger" data-info="${style.info}">${style.label}</span>`
+		: `<span class="zone-text ${style.className}">${style.label}</span>`;
+	return `
+                        <tr>
+                            <td>${label}</td>
+                            <td>${zone.min.toFixed(0)}</td>
+                            <td>${zone.max.toFixed(0)}</td>
+                        </tr>`;
+}
+
 // 显示结果
 function displayResults(data) {
 	const weight = Number(document.getElementById('weight').value) || 0;
@@ -94,41 +132,7 @@ function displayResults(data) {
                         </tr>
                     </thead>
                     <tbody>
-                        <tr>
-                            <td><span class="zone-text recovery-text info-trigger" data-info="recovery">恢复区间</span></td>
-                            <td>${data.training_zones.recovery_zone.min.toFixed(0)}</td>
-                            <td>${data.training_zones.recovery_zone.max.toFixed(0)}</td>
-                        </tr>
-                        <tr>
-                            <td><span class="zone-text endurance-text info-trigger" data-info="endurance">耐力区间</span></td>
-                            <td>${data.training_zones.endurance_zone.min.toFixed(0)}</td>
-                            <td>${data.training_zones.endurance_zone.max.toFixed(0)}</td>
-                        </tr>
-                        <tr>
-                            <td><span class="zone-text tempo-text info-trigger" data-info="tempo">节奏区间</span></td>
-                            <td>${data.training_zones.tempo_zone.min.toFixed(0)}</td>
-                            <td>${data.training_zones.tempo_zone.max.toFixed(0)}</td>
-                        </tr>
-                        <tr>
-                            <td><span class="zone-text threshold-text info-trigger" data-info="threshold">阈值区间</span></td>
-                            <td>${data.training_zones.threshold_zone.min.toFixed(0)}</td>
-                            <td>${data.training_zones.threshold_zone.max.toFixed(0)}</td>
-                        </tr>
-                        <tr>
-                            <td><span class="zone-text vo2max-text info-trigger" data-info="vo2max_zone">VO₂Max区间</span></td>
-                            <td>${data.training_zones.vo2max_zone.min.toFixed(0)}</td>
-                            <td>${data.training_zones.vo2max_zone.max.toFixed(0)}</td>
-                        </tr>
-                        <tr>
-                            <td><span class="zone-text anaerobic-text info-trigger" data-info="anaerobic">无氧区间</span></td>
-                            <td>${data.training_zones.anaerobic_zone.min.toFixed(0)}</td>
-                            <td>${data.training_zones.anaerobic_zone.max.toFixed(0)}</td>
-                        </tr>
-                        <tr>
-                            <td><span class="zone-text neuromuscular-text info-trigger" data-info="neuromuscular">神经肌肉区间</span></td>
-                            <td>${data.training_zones.neuromuscular_zone.min.toFixed(0)}</td>
-                            <td>${data.training_zones.neuromuscular_zone.max.toFixed(0)}</td>
-                        </tr>
+                        ${data.training_zones.map(zoneRow).join('')}
                     </tbody>
                 </table>
             </div>`;
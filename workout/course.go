@@ -0,0 +1,61 @@
+package workout
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// writeCourse 写入 ERG（percent 为 false）或 MRC（percent 为 true）文件
+//
+// 两种格式的数据部分都是按时间（分钟）排列的功率点，相邻两点之间线性变化，
+// 因此每段写入起点与终点两行，恒定功率的段两行功率相同。
+func writeCourse(w io.Writer, workout *Workout, cp float64, percent bool) error {
+	bw := bufio.NewWriter(w)
+	unit := "WATTS"
+	if percent {
+		unit = "PERCENT"
+	}
+
+	fmt.Fprintln(bw, "[COURSE HEADER]")
+	fmt.Fprintln(bw, "VERSION = 2")
+	fmt.Fprintln(bw, "UNITS = METRIC")
+	fmt.Fprintf(bw, "DESCRIPTION = %s\n", headerValue(workout.Description))
+	fmt.Fprintf(bw, "FILE NAME = %s\n", headerValue(workout.Name))
+	if !percent {
+		fmt.Fprintf(bw, "FTP = %.0f\n", cp)
+	}
+	fmt.Fprintf(bw, "MINUTES %s\n", unit)
+	fmt.Fprintln(bw, "[END COURSE HEADER]")
+	fmt.Fprintln(bw, "[COURSE DATA]")
+
+	elapsed := 0
+	for _, s := range workout.Steps {
+		start, end := s.ratios(cp)
+		value := func(ratio float64) string {
+			if percent {
+				return strconv.FormatFloat(round(ratio*100, 1), 'f', -1, 64)
+			}
+			return strconv.FormatFloat(math.Round(ratio*cp), 'f', 0, 64)
+		}
+		fmt.Fprintf(bw, "%.2f\t%s\n", float64(elapsed)/60, value(start))
+		elapsed += s.Duration
+		fmt.Fprintf(bw, "%.2f\t%s\n", float64(elapsed)/60, value(end))
+	}
+	fmt.Fprintln(bw, "[END COURSE DATA]")
+	return bw.Flush()
+}
+
+// headerValue 将换行替换为空格，防止用户输入的名称与描述插入额外的行
+func headerValue(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
+
+// round 保留 digits 位小数
+func round(v float64, digits int) float64 {
+	scale := math.Pow10(digits)
+	return math.Round(v*scale) / scale
+}
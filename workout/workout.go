@@ -0,0 +1,141 @@
+// Package workout 结构化训练课程及其导出，支持 Zwift 的 ZWO 以及骑行台通用的 ERG、MRC 格式
+package workout
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Equationzhao/power/criticalpower"
+)
+
+// PowerUnit 功率的单位
+type PowerUnit string
+
+const (
+	UnitCP    PowerUnit = "cp"    // CP 的比例，例如 1.2 表示 120% CP
+	UnitWatts PowerUnit = "watts" // 绝对功率（瓦）
+)
+
+// Step 训练课程中的一段
+type Step struct {
+	Duration int       // 时长（秒）
+	Power    float64   // 起始功率
+	PowerEnd *float64  // 结束功率，nil 表示恒定功率，否则从 Power 线性变化到 PowerEnd
+	Unit     PowerUnit // 功率单位，空字符串视为 UnitCP
+}
+
+// Workout 结构化训练课程
+type Workout struct {
+	Name        string
+	Description string
+	Steps       []Step
+}
+
+// Format 导出的文件格式
+type Format string
+
+const (
+	ZWO Format = "zwo" // Zwift 训练文件，功率为 FTP 的比例，这里以 CP 作为 FTP
+	ERG Format = "erg" // 以分钟与瓦表示的功率曲线
+	MRC Format = "mrc" // 以分钟与 FTP 百分比表示的功率曲线
+)
+
+// ParseFormat 解析文件格式
+func ParseFormat(s string) (Format, error) {
+	switch format := Format(strings.ToLower(s)); format {
+	case ZWO, ERG, MRC:
+		return format, nil
+	default:
+		return "", errors.New("不支持的训练文件格式: " + s)
+	}
+}
+
+// ContentType 返回文件格式对应的 MIME 类型
+func (f Format) ContentType() string {
+	if f == ZWO {
+		return "application/xml"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// FromIntervals 将间歇训练转换为训练课程，功率为绝对功率
+func FromIntervals(name string, session criticalpower.IntervalSession) *Workout {
+	w := &Workout{Name: name}
+	for rep := range session.Reps {
+		if rep > 0 && session.RestDuration > 0 {
+			w.Steps = append(w.Steps, Step{Duration: session.RestDuration, Power: session.RestPower, Unit: UnitWatts})
+		}
+		w.Steps = append(w.Steps, Step{Duration: session.WorkDuration, Power: session.WorkPower, Unit: UnitWatts})
+	}
+	return w
+}
+
+// Validate 检查训练课程是否有效
+func (w *Workout) Validate() error {
+	if len(w.Steps) == 0 {
+		return errors.New("训练课程中没有内容")
+	}
+	for i, s := range w.Steps {
+		if s.Duration <= 0 {
+			return fmt.Errorf("第%d段的时长必须大于0", i+1)
+		}
+		if s.Power < 0 || (s.PowerEnd != nil && *s.PowerEnd < 0) {
+			return fmt.Errorf("第%d段的功率不能为负", i+1)
+		}
+		switch s.Unit {
+		case "", UnitCP, UnitWatts:
+		default:
+			return fmt.Errorf("第%d段的功率单位未知: %s", i+1, s.Unit)
+		}
+	}
+	return nil
+}
+
+// Duration 返回训练课程的总时长（秒）
+func (w *Workout) Duration() int {
+	total := 0
+	for _, s := range w.Steps {
+		total += s.Duration
+	}
+	return total
+}
+
+// ratio 将功率转换为 CP 的比例
+func (s Step) ratio(power, cp float64) float64 {
+	if s.Unit == UnitWatts {
+		return power / cp
+	}
+	return power
+}
+
+// ratios 返回起始与结束功率占 CP 的比例
+func (s Step) ratios(cp float64) (start, end float64) {
+	start = s.ratio(s.Power, cp)
+	end = start
+	if s.PowerEnd != nil {
+		end = s.ratio(*s.PowerEnd, cp)
+	}
+	return start, end
+}
+
+// Write 将训练课程以给定格式写入 w，cp 用于在相对功率与绝对功率之间换算
+func Write(w io.Writer, workout *Workout, cp float64, format Format) error {
+	if cp <= 0 {
+		return errors.New("CP 必须大于0")
+	}
+	if err := workout.Validate(); err != nil {
+		return err
+	}
+	switch format {
+	case ZWO:
+		return writeZWO(w, workout, cp)
+	case ERG:
+		return writeCourse(w, workout, cp, false)
+	case MRC:
+		return writeCourse(w, workout, cp, true)
+	default:
+		return errors.New("不支持的训练文件格式: " + string(format))
+	}
+}
@@ -0,0 +1,132 @@
+package workout_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/Equationzhao/power/criticalpower"
+	"github.com/Equationzhao/power/workout"
+)
+
+func ptr(v float64) *float64 { return &v }
+
+func testWorkout() *workout.Workout {
+	return &workout.Workout{
+		Name:        "3x3",
+		Description: "VO2max",
+		Steps: []workout.Step{
+			{Duration: 600, Power: 0.5, PowerEnd: ptr(0.75)},
+			{Duration: 180, Power: 300, Unit: workout.UnitWatts},
+			{Duration: 120, Power: 0.5},
+		},
+	}
+}
+
+func TestWriteZWO(t *testing.T) {
+	var buf bytes.Buffer
+	if err := workout.Write(&buf, testWorkout(), 250, workout.ZWO); err != nil {
+		t.Fatalf("导出失败: %v", err)
+	}
+
+	var file struct {
+		Name    string `xml:"name"`
+		Workout struct {
+			Steps []struct {
+				XMLName   xml.Name
+				Duration  int     `xml:"Duration,attr"`
+				Power     float64 `xml:"Power,attr"`
+				PowerLow  float64 `xml:"PowerLow,attr"`
+				PowerHigh float64 `xml:"PowerHigh,attr"`
+			} `xml:",any"`
+		} `xml:"workout"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &file); err != nil {
+		t.Fatalf("解析导出的 ZWO 失败: %v\n%s", err, buf.String())
+	}
+	steps := file.Workout.Steps
+	if file.Name != "3x3" || len(steps) != 3 {
+		t.Fatalf("ZWO = %+v", file)
+	}
+	if s := steps[0]; s.XMLName.Local != "Ramp" || s.PowerLow != 0.5 || s.PowerHigh != 0.75 {
+		t.Errorf("第1段 = %+v", s)
+	}
+	// 绝对功率按 CP 换算为比例
+	if s := steps[1]; s.XMLName.Local != "SteadyState" || s.Power != 1.2 || s.Duration != 180 {
+		t.Errorf("第2段 = %+v", s)
+	}
+}
+
+func TestWriteCourse(t *testing.T) {
+	var erg, mrc bytes.Buffer
+	if err := workout.Write(&erg, testWorkout(), 250, workout.ERG); err != nil {
+		t.Fatalf("导出 ERG 失败: %v", err)
+	}
+	if err := workout.Write(&mrc, testWorkout(), 250, workout.MRC); err != nil {
+		t.Fatalf("导出 MRC 失败: %v", err)
+	}
+
+	for _, want := range []string{"FTP = 250", "MINUTES WATTS", "0.00\t125\n10.00\t188\n10.00\t300\n13.00\t300\n"} {
+		if !strings.Contains(erg.String(), want) {
+			t.Errorf("ERG 中缺少 %q:\n%s", want, erg.String())
+		}
+	}
+	for _, want := range []string{"MINUTES PERCENT", "10.00\t120\n13.00\t120\n13.00\t50\n15.00\t50\n"} {
+		if !strings.Contains(mrc.String(), want) {
+			t.Errorf("MRC 中缺少 %q:\n%s", want, mrc.String())
+		}
+	}
+}
+
+// TestWriteZeroPower 测试功率为 0 的段仍然写入功率属性
+func TestWriteZeroPower(t *testing.T) {
+	w := &workout.Workout{
+		Name: "zero",
+		Steps: []workout.Step{
+			{Duration: 60, Power: 0},
+			{Duration: 60, Power: 0, PowerEnd: ptr(0.5)},
+			{Duration: 60, Power: 0.5, PowerEnd: ptr(0)},
+		},
+	}
+	var buf bytes.Buffer
+	if err := workout.Write(&buf, w, 250, workout.ZWO); err != nil {
+		t.Fatalf("导出失败: %v", err)
+	}
+	for _, want := range []string{
+		`<SteadyState Duration="60" Power="0">`,
+		`<Ramp Duration="60" PowerLow="0" PowerHigh="0.5">`,
+		`<Ramp Duration="60" PowerLow="0.5" PowerHigh="0">`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("ZWO 中缺少 %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+// TestWriteCourseHeaderNewline 测试名称与描述中的换行不会插入额外的行
+func TestWriteCourseHeaderNewline(t *testing.T) {
+	w := testWorkout()
+	w.Name = "3x3\r\n[END COURSE HEADER]\n[COURSE DATA]\n0.00\t2000"
+	w.Description = "VO2max\nFTP = 999"
+	var buf bytes.Buffer
+	if err := workout.Write(&buf, w, 250, workout.ERG); err != nil {
+		t.Fatalf("导出失败: %v", err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if lines[3] != "DESCRIPTION = VO2max FTP = 999" || lines[5] != "FTP = 250" || lines[9] != "0.00\t125" {
+		t.Errorf("名称与描述插入了额外的行:\n%s", buf.String())
+	}
+}
+
+func TestFromIntervals(t *testing.T) {
+	w := workout.FromIntervals("6x3", criticalpower.IntervalSession{
+		Reps: 6, WorkDuration: 180, WorkPower: 300, RestDuration: 180, RestPower: 125,
+	})
+	if len(w.Steps) != 11 || w.Duration() != 6*180+5*180 {
+		t.Errorf("段数 = %d, 时长 = %d", len(w.Steps), w.Duration())
+	}
+	if err := (&workout.Workout{}).Validate(); err == nil {
+		t.Error("空课程应返回错误")
+	}
+}
@@ -0,0 +1,65 @@
+package workout
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// zwoStep ZWO 文件中的一段，恒定功率为 SteadyState，线性变化为 Ramp
+// 功率使用指针，只省略该类型没有的属性，功率为 0 时仍然写入
+type zwoStep struct {
+	XMLName   xml.Name
+	Duration  int      `xml:"Duration,attr"`
+	Power     *float64 `xml:"Power,attr,omitempty"`
+	PowerLow  *float64 `xml:"PowerLow,attr,omitempty"`
+	PowerHigh *float64 `xml:"PowerHigh,attr,omitempty"`
+}
+
+type zwoWorkout struct {
+	Steps []zwoStep `xml:",any"`
+}
+
+type zwoFile struct {
+	XMLName     xml.Name   `xml:"workout_file"`
+	Author      string     `xml:"author"`
+	Name        string     `xml:"name"`
+	Description string     `xml:"description"`
+	SportType   string     `xml:"sportType"`
+	Workout     zwoWorkout `xml:"workout"`
+}
+
+// writeZWO 写入 Zwift 训练文件，功率以 CP 的比例表示
+// Ramp 的 PowerLow 与 PowerHigh 分别为起始与结束功率，因此也可以表示递减的功率
+func writeZWO(w io.Writer, workout *Workout, cp float64) error {
+	file := zwoFile{
+		Name:        workout.Name,
+		Description: workout.Description,
+		SportType:   "bike",
+		Workout:     zwoWorkout{Steps: make([]zwoStep, len(workout.Steps))},
+	}
+	for i, s := range workout.Steps {
+		start, end := s.ratios(cp)
+		if start == end {
+			file.Workout.Steps[i] = zwoStep{XMLName: xml.Name{Local: "SteadyState"}, Duration: s.Duration, Power: ratio(start)}
+		} else {
+			file.Workout.Steps[i] = zwoStep{XMLName: xml.Name{Local: "Ramp"}, Duration: s.Duration, PowerLow: ratio(start), PowerHigh: ratio(end)}
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "    ")
+	if err := encoder.Encode(file); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ratio 返回保留 3 位小数的功率比例
+func ratio(v float64) *float64 {
+	r := round(v, 3)
+	return &r
+}